@@ -0,0 +1,74 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// NamespacedSourceReference references the replicated ConfigMap or Secret in any namespace.
+type NamespacedSourceReference struct {
+	SourceReference `json:",inline"`
+
+	// Namespace of the source object.
+	// +kubebuilder:validation:MinLength=1
+	Namespace string `json:"namespace"`
+}
+
+// ClusterReplicationPolicySpec defines the desired state of ClusterReplicationPolicy.
+type ClusterReplicationPolicySpec struct {
+	// Source references the replicated object.
+	Source NamespacedSourceReference `json:"source"`
+
+	ReplicationSpec `json:",inline"`
+}
+
+// ClusterReplicationPolicy replicates a ConfigMap or Secret of any namespace into other namespaces. It takes
+// precedence over ReplicationPolicies and annotations of the same source.
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:scope=Cluster,shortName=crp
+// +kubebuilder:printcolumn:name="Kind",type=string,JSONPath=`.spec.source.kind`
+// +kubebuilder:printcolumn:name="Namespace",type=string,JSONPath=`.spec.source.namespace`
+// +kubebuilder:printcolumn:name="Source",type=string,JSONPath=`.spec.source.name`
+// +kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+type ClusterReplicationPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ClusterReplicationPolicySpec `json:"spec"`
+	Status ReplicationPolicyStatus      `json:"status,omitempty"`
+}
+
+// ClusterReplicationPolicyList contains a list of ClusterReplicationPolicy.
+// +kubebuilder:object:root=true
+type ClusterReplicationPolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []ClusterReplicationPolicy `json:"items"`
+}
+
+// GetSourceKey returns the key of the replicated object.
+func (p *ClusterReplicationPolicy) GetSourceKey() types.NamespacedName {
+	return types.NamespacedName{Namespace: p.Spec.Source.Namespace, Name: p.Spec.Source.Name}
+}
+
+// GetSourceKind returns the kind of the replicated object.
+func (p *ClusterReplicationPolicy) GetSourceKind() SourceKind {
+	return p.Spec.Source.Kind
+}
+
+// GetReplicationSpec returns how the source is replicated.
+func (p *ClusterReplicationPolicy) GetReplicationSpec() *ReplicationSpec {
+	return &p.Spec.ReplicationSpec
+}
+
+// GetReplicationStatus returns the observed state of the replication.
+func (p *ClusterReplicationPolicy) GetReplicationStatus() *ReplicationPolicyStatus {
+	return &p.Status
+}
+
+func init() {
+	SchemeBuilder.Register(&ClusterReplicationPolicy{}, &ClusterReplicationPolicyList{})
+}
@@ -0,0 +1,151 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// SourceKind is the kind of a replicated object.
+// +kubebuilder:validation:Enum=ConfigMap;Secret
+type SourceKind string
+
+const (
+	SourceKindConfigMap SourceKind = "ConfigMap"
+	SourceKindSecret    SourceKind = "Secret"
+)
+
+// DeletionPolicy defines what happens to replicas which are no longer desired.
+// +kubebuilder:validation:Enum=Delete;Retain
+type DeletionPolicy string
+
+const (
+	// DeletionPolicyDelete deletes replicas which are no longer desired.
+	DeletionPolicyDelete DeletionPolicy = "Delete"
+	// DeletionPolicyRetain keeps replicas which are no longer desired as unmanaged objects.
+	DeletionPolicyRetain DeletionPolicy = "Retain"
+)
+
+// ConditionTypeReady indicates whether the source was replicated to all targets.
+const ConditionTypeReady = "Ready"
+
+// SourceReference references the replicated ConfigMap or Secret.
+type SourceReference struct {
+	// Kind of the source object.
+	Kind SourceKind `json:"kind"`
+
+	// Name of the source object within the namespace of the policy.
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+}
+
+// Targets selects the namespaces replicas are created in. A namespace is targeted if it is listed by name or matches
+// the selector.
+type Targets struct {
+	// Namespaces lists the names of the target namespaces.
+	// +optional
+	Namespaces []string `json:"namespaces,omitempty"`
+
+	// NamespaceSelector selects target namespaces by their labels.
+	// +optional
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+}
+
+// KeyFilter filters the keys of the source which are replicated. Entries may contain shell file name patterns.
+type KeyFilter struct {
+	// Include lists the keys that are replicated. All keys are replicated if empty.
+	// +optional
+	Include []string `json:"include,omitempty"`
+
+	// Exclude lists the keys that are not replicated.
+	// +optional
+	Exclude []string `json:"exclude,omitempty"`
+}
+
+// ReplicationPolicySpec defines the desired state of ReplicationPolicy.
+type ReplicationPolicySpec struct {
+	// Source references the replicated object.
+	Source SourceReference `json:"source"`
+
+	ReplicationSpec `json:",inline"`
+}
+
+// ReplicationSpec defines how a source is replicated.
+type ReplicationSpec struct {
+	// Targets selects the namespaces the source is replicated to.
+	Targets Targets `json:"targets"`
+
+	// Keys filters the replicated keys of the source.
+	// +optional
+	Keys *KeyFilter `json:"keys,omitempty"`
+
+	// DeletionPolicy defines what happens to replicas which are no longer targeted or when the policy is deleted.
+	// +kubebuilder:default=Delete
+	// +optional
+	DeletionPolicy DeletionPolicy `json:"deletionPolicy,omitempty"`
+}
+
+// ReplicationPolicyStatus defines the observed state of ReplicationPolicy and ClusterReplicationPolicy.
+type ReplicationPolicyStatus struct {
+	// ObservedGeneration is the generation of the policy the status was written for.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// ReplicatedTo lists the namespaces the source was replicated to.
+	// +optional
+	ReplicatedTo []string `json:"replicatedTo,omitempty"`
+
+	// Conditions describe the state of the replication.
+	// +listType=map
+	// +listMapKey=type
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// ReplicationPolicy replicates a ConfigMap or Secret of its namespace into other namespaces.
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:shortName=rp
+// +kubebuilder:printcolumn:name="Kind",type=string,JSONPath=`.spec.source.kind`
+// +kubebuilder:printcolumn:name="Source",type=string,JSONPath=`.spec.source.name`
+// +kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+type ReplicationPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ReplicationPolicySpec   `json:"spec"`
+	Status ReplicationPolicyStatus `json:"status,omitempty"`
+}
+
+// ReplicationPolicyList contains a list of ReplicationPolicy.
+// +kubebuilder:object:root=true
+type ReplicationPolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []ReplicationPolicy `json:"items"`
+}
+
+// GetSourceKey returns the key of the replicated object.
+func (p *ReplicationPolicy) GetSourceKey() types.NamespacedName {
+	return types.NamespacedName{Namespace: p.Namespace, Name: p.Spec.Source.Name}
+}
+
+// GetSourceKind returns the kind of the replicated object.
+func (p *ReplicationPolicy) GetSourceKind() SourceKind {
+	return p.Spec.Source.Kind
+}
+
+// GetReplicationSpec returns how the source is replicated.
+func (p *ReplicationPolicy) GetReplicationSpec() *ReplicationSpec {
+	return &p.Spec.ReplicationSpec
+}
+
+// GetReplicationStatus returns the observed state of the replication.
+func (p *ReplicationPolicy) GetReplicationStatus() *ReplicationPolicyStatus {
+	return &p.Status
+}
+
+func init() {
+	SchemeBuilder.Register(&ReplicationPolicy{}, &ReplicationPolicyList{})
+}
@@ -1,20 +1,35 @@
 package main
 
 import (
+	"context"
 	"os"
 
-	"github.com/c0deltin/replik8or/internal/replicator"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	ctrlconfig "sigs.k8s.io/controller-runtime/pkg/client/config"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/manager/signals"
 	"sigs.k8s.io/controller-runtime/pkg/metrics/server"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
 
+	"github.com/c0deltin/replik8or/api/v1alpha1"
 	"github.com/c0deltin/replik8or/internal/config"
+	"github.com/c0deltin/replik8or/internal/controller/orphan"
+	"github.com/c0deltin/replik8or/internal/controller/policy"
+	"github.com/c0deltin/replik8or/internal/controller/report"
 	"github.com/c0deltin/replik8or/internal/controller/source"
+	"github.com/c0deltin/replik8or/internal/controller/staleness"
+	"github.com/c0deltin/replik8or/internal/controller/target"
+	"github.com/c0deltin/replik8or/internal/namespaces"
+	"github.com/c0deltin/replik8or/internal/replicator"
+	"github.com/c0deltin/replik8or/internal/tracing"
+	replik8orwebhook "github.com/c0deltin/replik8or/internal/webhook"
 )
 
 func main() {
@@ -29,30 +44,86 @@ func main() {
 		os.Exit(1)
 	}
 
+	shutdownTracing, err := tracing.Setup(ctx, cfg)
+	if err != nil {
+		setupLog.Error(err, "setup tracing")
+		os.Exit(1)
+	}
+
 	ctrlCfg, err := ctrlconfig.GetConfig()
 	if err != nil {
 		setupLog.Error(err, "reading kubernetes configuration")
 		os.Exit(1)
 	}
+	ctrlCfg.QPS = cfg.KubeAPIQPS
+	ctrlCfg.Burst = cfg.KubeAPIBurst
 
 	scheme := runtime.NewScheme()
 	_ = corev1.AddToScheme(scheme)
+	_ = authenticationv1.AddToScheme(scheme)
+	_ = authorizationv1.AddToScheme(scheme)
+	_ = v1alpha1.AddToScheme(scheme)
+
+	cacheOptions := cache.Options{
+		SyncPeriod: &cfg.CacheSyncPeriod,
+		// only the data of sources and pull targets is cached, sources of policies and replicas are read from the API
+		// server
+		ByObject: map[client.Object]cache.ByObject{
+			&corev1.ConfigMap{}: {Transform: replicator.StripUnreadData},
+			&corev1.Secret{}:    {Transform: replicator.StripUnreadData},
+		},
+	}
+	if len(cfg.WatchNamespaces) > 0 {
+		cacheOptions.DefaultNamespaces = make(map[string]cache.Config, len(cfg.WatchNamespaces))
+		for _, namespace := range cfg.WatchNamespaces {
+			cacheOptions.DefaultNamespaces[namespace] = cache.Config{}
+		}
+	}
 
 	mgr, err := manager.New(ctrlCfg, manager.Options{
 		Scheme: scheme,
+		Cache:  cacheOptions,
 		Metrics: server.Options{
 			BindAddress: cfg.MetricsAddress,
 		},
 		HealthProbeBindAddress: cfg.HealthProbeAddress,
+		WebhookServer: webhook.NewServer(webhook.Options{
+			Port:    cfg.WebhookPort,
+			CertDir: cfg.WebhookCertDir,
+		}),
+		LeaderElection:          cfg.LeaderElect,
+		LeaderElectionNamespace: cfg.LeaderElectionNamespace,
+		LeaderElectionID:        cfg.LeaderElectionID,
+		LeaseDuration:           &cfg.LeaseDuration,
+		RenewDeadline:           &cfg.RenewDeadline,
+		RetryPeriod:             &cfg.RetryPeriod,
+		// the process exits right after the manager stopped, so the lease can be released for a fast failover
+		LeaderElectionReleaseOnCancel: true,
 	})
 	if err != nil {
 		setupLog.Error(err, "setup controller manager")
 		os.Exit(1)
 	}
 
+	// the events.k8s.io based recorder would require additional RBAC rules for existing deployments
+	recorder := mgr.GetEventRecorderFor("replik8or") //nolint:staticcheck
+
+	// watching namespaces requires cluster-wide permissions, a restricted operator only targets the given namespaces
+	namespaceIndex := namespaces.NewStaticIndex(cfg.WatchNamespaces...)
+	if len(cfg.WatchNamespaces) == 0 {
+		namespaceIndex = namespaces.NewIndex()
+		if err := namespaceIndex.SetupWithManager(ctx, mgr); err != nil {
+			setupLog.Error(err, "setup namespace index")
+			os.Exit(1)
+		}
+	}
+
 	configMapReconciler := source.NewReconciler[*corev1.ConfigMap](
 		mgr.GetClient(),
+		mgr.GetAPIReader(),
+		namespaceIndex,
 		cfg,
+		recorder,
 		replicator.EmptyConfigMap,
 		replicator.EmptyConfigMapList,
 	)
@@ -63,7 +134,10 @@ func main() {
 
 	secretReconciler := source.NewReconciler[*corev1.Secret](
 		mgr.GetClient(),
+		mgr.GetAPIReader(),
+		namespaceIndex,
 		cfg,
+		recorder,
 		replicator.EmptySecret,
 		replicator.EmptySecretList,
 	)
@@ -72,8 +146,200 @@ func main() {
 		os.Exit(1)
 	}
 
+	if err := target.NewReconciler[*corev1.ConfigMap](
+		mgr.GetClient(),
+		cfg,
+		recorder,
+		replicator.EmptyConfigMap,
+		replicator.EmptyConfigMapList,
+	).SetupWithManager("target-configmap", mgr); err != nil {
+		setupLog.Error(err, "setup target reconciler", "controller", "ConfigMap")
+		os.Exit(1)
+	}
+
+	if err := target.NewReconciler[*corev1.Secret](
+		mgr.GetClient(),
+		cfg,
+		recorder,
+		replicator.EmptySecret,
+		replicator.EmptySecretList,
+	).SetupWithManager("target-secret", mgr); err != nil {
+		setupLog.Error(err, "setup target reconciler", "controller", "Secret")
+		os.Exit(1)
+	}
+
+	// stale replicas are requeued by the controller replicating them
+	requeue := staleness.Requeue{}
+	if cfg.EnablePolicies {
+		policyReconciler := policy.NewReconciler(
+			mgr.GetClient(),
+			mgr.GetAPIReader(),
+			namespaceIndex,
+			cfg,
+			recorder,
+		)
+		if err := policyReconciler.SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "setup policy reconciler", "controller", "ReplicationPolicy")
+			os.Exit(1)
+		}
+		requeue.Policies = policyReconciler.Requeue()
+
+		clusterPolicyReconciler := policy.NewClusterReconciler(
+			mgr.GetClient(),
+			mgr.GetAPIReader(),
+			namespaceIndex,
+			cfg,
+			recorder,
+		)
+		if err := clusterPolicyReconciler.SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "setup policy reconciler", "controller", "ClusterReplicationPolicy")
+			os.Exit(1)
+		}
+		requeue.ClusterPolicies = clusterPolicyReconciler.Requeue()
+	}
+
+	if cfg.EnableWebhooks {
+		if err := replik8orwebhook.NewAnnotationValidator(
+			mgr.GetClient(),
+			namespaceIndex,
+			cfg,
+			replicator.EmptyConfigMap,
+		).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "setup annotation webhook", "kind", "ConfigMap")
+			os.Exit(1)
+		}
+
+		if err := replik8orwebhook.NewAnnotationValidator(
+			mgr.GetClient(),
+			namespaceIndex,
+			cfg,
+			replicator.EmptySecret,
+		).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "setup annotation webhook", "kind", "Secret")
+			os.Exit(1)
+		}
+
+		if cfg.ProtectReplicas {
+			operator, err := replik8orwebhook.OperatorUsername(ctx, mgr.GetClient())
+			if err != nil {
+				setupLog.Error(err, "setup replica webhook")
+				os.Exit(1)
+			}
+
+			if err := replik8orwebhook.NewReplicaValidator(
+				cfg,
+				operator,
+				replicator.EmptyConfigMap,
+			).SetupWithManager(mgr); err != nil {
+				setupLog.Error(err, "setup replica webhook", "kind", "ConfigMap")
+				os.Exit(1)
+			}
+
+			if err := replik8orwebhook.NewReplicaValidator(
+				cfg,
+				operator,
+				replicator.EmptySecret,
+			).SetupWithManager(mgr); err != nil {
+				setupLog.Error(err, "setup replica webhook", "kind", "Secret")
+				os.Exit(1)
+			}
+		}
+	}
+
+	configMapOrphans := orphan.NewCollector[*corev1.ConfigMap](
+		mgr.GetClient(),
+		cfg,
+		recorder,
+		replicator.EmptyConfigMap,
+		replicator.EmptyConfigMapList,
+	)
+	secretOrphans := orphan.NewCollector[*corev1.Secret](
+		mgr.GetClient(),
+		cfg,
+		recorder,
+		replicator.EmptySecret,
+		replicator.EmptySecretList,
+	)
+
+	configMapRequeue := requeue
+	configMapRequeue.Sources = configMapReconciler.Requeue()
+	configMapStaleness := staleness.NewChecker[*corev1.ConfigMap](
+		mgr.GetClient(),
+		mgr.GetAPIReader(),
+		cfg,
+		recorder,
+		replicator.EmptyConfigMap,
+		replicator.EmptyConfigMapList,
+		configMapOrphans,
+		configMapRequeue,
+	)
+	secretRequeue := requeue
+	secretRequeue.Sources = secretReconciler.Requeue()
+	secretStaleness := staleness.NewChecker[*corev1.Secret](
+		mgr.GetClient(),
+		mgr.GetAPIReader(),
+		cfg,
+		recorder,
+		replicator.EmptySecret,
+		replicator.EmptySecretList,
+		secretOrphans,
+		secretRequeue,
+	)
+
+	if cfg.StalenessCheckInterval > 0 {
+		if err := mgr.Add(configMapStaleness); err != nil {
+			setupLog.Error(err, "setup staleness checker", "kind", "ConfigMap")
+			os.Exit(1)
+		}
+
+		if err := mgr.Add(secretStaleness); err != nil {
+			setupLog.Error(err, "setup staleness checker", "kind", "Secret")
+			os.Exit(1)
+		}
+	}
+
+	if cfg.OrphanCheckInterval > 0 {
+		if err := mgr.Add(configMapOrphans); err != nil {
+			setupLog.Error(err, "setup orphan collector", "kind", "ConfigMap")
+			os.Exit(1)
+		}
+
+		if err := mgr.Add(secretOrphans); err != nil {
+			setupLog.Error(err, "setup orphan collector", "kind", "Secret")
+			os.Exit(1)
+		}
+	}
+
+	if err := mgr.Add(report.NewRunnable(
+		mgr.GetClient(),
+		mgr.GetAPIReader(),
+		mgr.GetCache(),
+		cfg,
+		report.NewKind[*corev1.ConfigMap](
+			mgr.GetClient(),
+			replicator.EmptyConfigMap,
+			replicator.EmptyConfigMapList,
+			configMapOrphans,
+			configMapStaleness,
+		),
+		report.NewKind[*corev1.Secret](
+			mgr.GetClient(),
+			replicator.EmptySecret,
+			replicator.EmptySecretList,
+			secretOrphans,
+			secretStaleness,
+		),
+	)); err != nil {
+		setupLog.Error(err, "setup startup report")
+		os.Exit(1)
+	}
+
 	if err := mgr.Start(ctx); err != nil {
 		setupLog.Error(err, "starting controller manager")
 		os.Exit(1)
 	}
+
+	if err := shutdownTracing(context.Background()); err != nil {
+		setupLog.Error(err, "shutting down tracing")
+	}
 }
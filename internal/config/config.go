@@ -3,6 +3,7 @@ package config
 import (
 	"flag"
 	"strings"
+	"time"
 
 	"github.com/go-viper/mapstructure/v2"
 	"github.com/spf13/pflag"
@@ -10,9 +11,44 @@ import (
 )
 
 type Config struct {
-	MetricsAddress       string   `mapstructure:"METRICS_ADDR"`
-	HealthProbeAddress   string   `mapstructure:"HEALTH_PROBE_ADDR"`
-	DisallowedNamespaces []string `mapstructure:"DISALLOWED_NAMESPACES"`
+	MetricsAddress             string        `mapstructure:"METRICS_ADDR"`
+	HealthProbeAddress         string        `mapstructure:"HEALTH_PROBE_ADDR"`
+	DisallowedNamespaces       []string      `mapstructure:"DISALLOWED_NAMESPACES"`
+	DryRun                     bool          `mapstructure:"DRY_RUN"`
+	OverwriteExisting          bool          `mapstructure:"OVERWRITE_EXISTING"`
+	StalenessCheckInterval     time.Duration `mapstructure:"STALENESS_CHECK_INTERVAL"`
+	StalenessThreshold         time.Duration `mapstructure:"STALENESS_THRESHOLD"`
+	StalenessRequeue           bool          `mapstructure:"STALENESS_REQUEUE"`
+	OrphanCheckInterval        time.Duration `mapstructure:"ORPHAN_CHECK_INTERVAL"`
+	OrphanDelete               bool          `mapstructure:"ORPHAN_DELETE"`
+	TracingEndpoint            string        `mapstructure:"TRACING_ENDPOINT"`
+	TracingInsecure            bool          `mapstructure:"TRACING_INSECURE"`
+	TracingSampleRatio         float64       `mapstructure:"TRACING_SAMPLE_RATIO"`
+	EnablePolicies             bool          `mapstructure:"ENABLE_POLICIES"`
+	AuthorizeTargets           bool          `mapstructure:"AUTHORIZE_TARGETS"`
+	DisallowedSourceNamespaces []string      `mapstructure:"DISALLOWED_SOURCE_NAMESPACES"`
+	EnableWebhooks             bool          `mapstructure:"ENABLE_WEBHOOKS"`
+	WebhookPort                int           `mapstructure:"WEBHOOK_PORT"`
+	WebhookCertDir             string        `mapstructure:"WEBHOOK_CERT_DIR"`
+	ProtectReplicas            bool          `mapstructure:"PROTECT_REPLICAS"`
+	BreakGlassGroups           []string      `mapstructure:"BREAK_GLASS_GROUPS"`
+	LeaderElect                bool          `mapstructure:"LEADER_ELECT"`
+	LeaderElectionNamespace    string        `mapstructure:"LEADER_ELECTION_NAMESPACE"`
+	LeaderElectionID           string        `mapstructure:"LEADER_ELECTION_ID"`
+	LeaseDuration              time.Duration `mapstructure:"LEADER_ELECTION_LEASE_DURATION"`
+	RenewDeadline              time.Duration `mapstructure:"LEADER_ELECTION_RENEW_DEADLINE"`
+	RetryPeriod                time.Duration `mapstructure:"LEADER_ELECTION_RETRY_PERIOD"`
+	MaxConcurrentReconciles    int           `mapstructure:"MAX_CONCURRENT_RECONCILES"`
+	FanoutConcurrency          int           `mapstructure:"FANOUT_CONCURRENCY"`
+	RateLimiterBaseDelay       time.Duration `mapstructure:"RATE_LIMITER_BASE_DELAY"`
+	RateLimiterMaxDelay        time.Duration `mapstructure:"RATE_LIMITER_MAX_DELAY"`
+	SourceWriteQPS             float64       `mapstructure:"SOURCE_WRITE_QPS"`
+	KubeAPIQPS                 float32       `mapstructure:"KUBE_API_QPS"`
+	KubeAPIBurst               int           `mapstructure:"KUBE_API_BURST"`
+	CacheSyncPeriod            time.Duration `mapstructure:"CACHE_SYNC_PERIOD"`
+	WatchNamespaces            []string      `mapstructure:"WATCH_NAMESPACES"`
+	ReportConfigMap            string        `mapstructure:"REPORT_CONFIGMAP"`
+	ReportNamespace            string        `mapstructure:"REPORT_NAMESPACE"`
 }
 
 var replacer = strings.NewReplacer("-", "_")
@@ -24,6 +60,41 @@ func Read() (*Config, error) {
 	flag.String("metrics-addr", "0", "The address the metric endpoint binds to. (default 0 = disabled)")
 	flag.String("health-probe-addr", "0", "The address the health probe binds to. (default 0 = disabled)")
 	flag.String("disallowed-namespaces", "", "A list (comma separated) of namespaces that are disallowed.")
+	flag.Bool("dry-run", false, "Only simulate writes of replicas using server-side dry-run.")
+	flag.Bool("overwrite-existing", true, "Overwrite existing objects in target namespaces which are not replicas of the source.")
+	flag.Duration("staleness-check-interval", 5*time.Minute, "The interval replicas are checked for being outdated. (0 = disabled)")
+	flag.Duration("staleness-threshold", time.Minute, "The time after a source change a replica not updated yet counts as stale.")
+	flag.Bool("staleness-requeue", false, "Requeue sources of stale replicas.")
+	flag.Duration("orphan-check-interval", time.Hour, "The interval replicas are checked for being orphaned. (0 = disabled)")
+	flag.Bool("orphan-delete", false, "Delete orphaned replicas instead of only reporting them.")
+	flag.String("tracing-endpoint", "", "The OTLP/HTTP endpoint (host:port) traces are exported to. (default empty = disabled)")
+	flag.Bool("tracing-insecure", false, "Export traces without TLS.")
+	flag.Float64("tracing-sample-ratio", 1, "The ratio of reconciliations to be traced.")
+	flag.Bool("enable-policies", false, "Reconcile ReplicationPolicy and ClusterReplicationPolicy resources. (requires the CRDs to be installed)")
+	flag.Bool("authorize-targets", false, "Only replicate to namespaces the ServiceAccount of the source may write to.")
+	flag.String("disallowed-source-namespaces", "", "A list (comma separated) of namespaces sources must not be located in.")
+	flag.Bool("enable-webhooks", false, "Serve the validating admission webhooks.")
+	flag.Int("webhook-port", 9443, "The port the webhook server binds to.")
+	flag.String("webhook-cert-dir", "", "The directory containing tls.crt and tls.key of the webhook server. (default empty = controller-runtime default)")
+	flag.Bool("protect-replicas", false, "Deny updates and deletions of replicas by anyone but the operator. (requires the webhooks)")
+	flag.String("break-glass-groups", "", "A list (comma separated) of groups allowed to modify replicas.")
+	flag.Bool("leader-elect", false, "Enable leader election, so only one instance of the operator is active.")
+	flag.String("leader-election-namespace", "", "The namespace of the leader election lease. (default empty = namespace of the pod)")
+	flag.String("leader-election-id", "replik8or.c0deltin.dev", "The name of the leader election lease.")
+	flag.Duration("leader-election-lease-duration", 15*time.Second, "The duration non-leaders wait before taking over leadership.")
+	flag.Duration("leader-election-renew-deadline", 10*time.Second, "The duration the leader retries renewing leadership before giving up.")
+	flag.Duration("leader-election-retry-period", 2*time.Second, "The duration between leader election attempts.")
+	flag.Int("max-concurrent-reconciles", 1, "The number of sources reconciled concurrently by each controller.")
+	flag.Int("fanout-concurrency", 10, "The number of replicas written concurrently for a single source.")
+	flag.Duration("rate-limiter-base-delay", 5*time.Millisecond, "The initial delay of retrying a failed reconciliation.")
+	flag.Duration("rate-limiter-max-delay", 1000*time.Second, "The maximum delay of retrying a failed reconciliation.")
+	flag.Float64("source-write-qps", 0, "The replica writes per second per source. (default 0 = unlimited)")
+	flag.Float64("kube-api-qps", 20, "The queries per second of the Kubernetes API client.")
+	flag.Int("kube-api-burst", 30, "The burst of the Kubernetes API client.")
+	flag.Duration("cache-sync-period", 10*time.Hour, "The interval all watched objects are reconciled again.")
+	flag.String("watch-namespaces", "", "A list (comma separated) of namespaces the operator is restricted to. (default empty = all namespaces)")
+	flag.String("report-configmap", "", "The name of the ConfigMap the startup report is written to. (default empty = disabled)")
+	flag.String("report-namespace", "", "The namespace of the report ConfigMap. (default empty = namespace of the pod)")
 
 	pflag.CommandLine.AddGoFlagSet(flag.CommandLine)
 	pflag.Parse()
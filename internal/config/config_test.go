@@ -5,6 +5,7 @@ import (
 	"os"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/spf13/pflag"
 	"github.com/stretchr/testify/assert"
@@ -12,9 +13,44 @@ import (
 
 func TestRead(t *testing.T) {
 	expected := &Config{
-		MetricsAddress:       "testing-metrics-addr",
-		HealthProbeAddress:   "testing-health-probe-addr",
-		DisallowedNamespaces: []string{"testing-foo", "testing-bar"},
+		MetricsAddress:             "testing-metrics-addr",
+		HealthProbeAddress:         "testing-health-probe-addr",
+		DisallowedNamespaces:       []string{"testing-foo", "testing-bar"},
+		DryRun:                     true,
+		OverwriteExisting:          false,
+		StalenessCheckInterval:     10 * time.Minute,
+		StalenessThreshold:         30 * time.Second,
+		StalenessRequeue:           true,
+		OrphanCheckInterval:        30 * time.Minute,
+		OrphanDelete:               true,
+		TracingEndpoint:            "testing-tracing-endpoint:4318",
+		TracingInsecure:            true,
+		TracingSampleRatio:         0.5,
+		EnablePolicies:             true,
+		AuthorizeTargets:           true,
+		DisallowedSourceNamespaces: []string{"testing-kube-system"},
+		EnableWebhooks:             true,
+		WebhookPort:                8443,
+		WebhookCertDir:             "testing-webhook-cert-dir",
+		ProtectReplicas:            true,
+		BreakGlassGroups:           []string{"testing-admins", "testing-oncall"},
+		LeaderElect:                true,
+		LeaderElectionNamespace:    "testing-leader-election-namespace",
+		LeaderElectionID:           "testing-leader-election-id",
+		LeaseDuration:              30 * time.Second,
+		RenewDeadline:              20 * time.Second,
+		RetryPeriod:                5 * time.Second,
+		MaxConcurrentReconciles:    4,
+		FanoutConcurrency:          20,
+		RateLimiterBaseDelay:       10 * time.Millisecond,
+		RateLimiterMaxDelay:        5 * time.Minute,
+		SourceWriteQPS:             20,
+		KubeAPIQPS:                 50,
+		KubeAPIBurst:               100,
+		CacheSyncPeriod:            time.Hour,
+		WatchNamespaces:            []string{"testing", "testing-replik8or"},
+		ReportConfigMap:            "testing-report",
+		ReportNamespace:            "testing-replik8or",
 	}
 
 	t.Run("environment variables", func(t *testing.T) {
@@ -24,6 +60,41 @@ func TestRead(t *testing.T) {
 		t.Setenv("METRICS_ADDR", expected.MetricsAddress)
 		t.Setenv("HEALTH_PROBE_ADDR", expected.HealthProbeAddress)
 		t.Setenv("DISALLOWED_NAMESPACES", strings.Join(expected.DisallowedNamespaces, ","))
+		t.Setenv("DRY_RUN", "true")
+		t.Setenv("OVERWRITE_EXISTING", "false")
+		t.Setenv("STALENESS_CHECK_INTERVAL", expected.StalenessCheckInterval.String())
+		t.Setenv("STALENESS_THRESHOLD", expected.StalenessThreshold.String())
+		t.Setenv("STALENESS_REQUEUE", "true")
+		t.Setenv("ORPHAN_CHECK_INTERVAL", expected.OrphanCheckInterval.String())
+		t.Setenv("ORPHAN_DELETE", "true")
+		t.Setenv("TRACING_ENDPOINT", expected.TracingEndpoint)
+		t.Setenv("TRACING_INSECURE", "true")
+		t.Setenv("TRACING_SAMPLE_RATIO", "0.5")
+		t.Setenv("ENABLE_POLICIES", "true")
+		t.Setenv("AUTHORIZE_TARGETS", "true")
+		t.Setenv("DISALLOWED_SOURCE_NAMESPACES", strings.Join(expected.DisallowedSourceNamespaces, ","))
+		t.Setenv("ENABLE_WEBHOOKS", "true")
+		t.Setenv("WEBHOOK_PORT", "8443")
+		t.Setenv("WEBHOOK_CERT_DIR", expected.WebhookCertDir)
+		t.Setenv("PROTECT_REPLICAS", "true")
+		t.Setenv("BREAK_GLASS_GROUPS", strings.Join(expected.BreakGlassGroups, ","))
+		t.Setenv("LEADER_ELECT", "true")
+		t.Setenv("LEADER_ELECTION_NAMESPACE", expected.LeaderElectionNamespace)
+		t.Setenv("LEADER_ELECTION_ID", expected.LeaderElectionID)
+		t.Setenv("LEADER_ELECTION_LEASE_DURATION", expected.LeaseDuration.String())
+		t.Setenv("LEADER_ELECTION_RENEW_DEADLINE", expected.RenewDeadline.String())
+		t.Setenv("LEADER_ELECTION_RETRY_PERIOD", expected.RetryPeriod.String())
+		t.Setenv("MAX_CONCURRENT_RECONCILES", "4")
+		t.Setenv("FANOUT_CONCURRENCY", "20")
+		t.Setenv("RATE_LIMITER_BASE_DELAY", expected.RateLimiterBaseDelay.String())
+		t.Setenv("RATE_LIMITER_MAX_DELAY", expected.RateLimiterMaxDelay.String())
+		t.Setenv("SOURCE_WRITE_QPS", "20")
+		t.Setenv("KUBE_API_QPS", "50")
+		t.Setenv("KUBE_API_BURST", "100")
+		t.Setenv("CACHE_SYNC_PERIOD", expected.CacheSyncPeriod.String())
+		t.Setenv("WATCH_NAMESPACES", strings.Join(expected.WatchNamespaces, ","))
+		t.Setenv("REPORT_CONFIGMAP", expected.ReportConfigMap)
+		t.Setenv("REPORT_NAMESPACE", expected.ReportNamespace)
 
 		actual, err := Read()
 
@@ -40,6 +111,41 @@ func TestRead(t *testing.T) {
 			"--metrics-addr", expected.MetricsAddress,
 			"--health-probe-addr", expected.HealthProbeAddress,
 			"--disallowed-namespaces", strings.Join(expected.DisallowedNamespaces, ","),
+			"--dry-run",
+			"--overwrite-existing=false",
+			"--staleness-check-interval", expected.StalenessCheckInterval.String(),
+			"--staleness-threshold", expected.StalenessThreshold.String(),
+			"--staleness-requeue",
+			"--orphan-check-interval", expected.OrphanCheckInterval.String(),
+			"--orphan-delete",
+			"--tracing-endpoint", expected.TracingEndpoint,
+			"--tracing-insecure",
+			"--tracing-sample-ratio", "0.5",
+			"--enable-policies",
+			"--authorize-targets",
+			"--disallowed-source-namespaces", strings.Join(expected.DisallowedSourceNamespaces, ","),
+			"--enable-webhooks",
+			"--webhook-port", "8443",
+			"--webhook-cert-dir", expected.WebhookCertDir,
+			"--protect-replicas",
+			"--break-glass-groups", strings.Join(expected.BreakGlassGroups, ","),
+			"--leader-elect",
+			"--leader-election-namespace", expected.LeaderElectionNamespace,
+			"--leader-election-id", expected.LeaderElectionID,
+			"--leader-election-lease-duration", expected.LeaseDuration.String(),
+			"--leader-election-renew-deadline", expected.RenewDeadline.String(),
+			"--leader-election-retry-period", expected.RetryPeriod.String(),
+			"--max-concurrent-reconciles", "4",
+			"--fanout-concurrency", "20",
+			"--rate-limiter-base-delay", expected.RateLimiterBaseDelay.String(),
+			"--rate-limiter-max-delay", expected.RateLimiterMaxDelay.String(),
+			"--source-write-qps", "20",
+			"--kube-api-qps", "50",
+			"--kube-api-burst", "100",
+			"--cache-sync-period", expected.CacheSyncPeriod.String(),
+			"--watch-namespaces", strings.Join(expected.WatchNamespaces, ","),
+			"--report-configmap", expected.ReportConfigMap,
+			"--report-namespace", expected.ReportNamespace,
 		}
 
 		actual, err := Read()
@@ -0,0 +1,19 @@
+package controller
+
+import (
+	"golang.org/x/sync/errgroup"
+)
+
+// FanOut calls fn for every namespace, running at most concurrency calls in parallel, and returns once all calls
+// returned. Failures are left to fn, so a single failing namespace does not hold back the others.
+func FanOut(concurrency int, namespaces []string, fn func(namespace string)) {
+	var group errgroup.Group
+	group.SetLimit(max(1, concurrency))
+	for _, namespace := range namespaces {
+		group.Go(func() error {
+			fn(namespace)
+			return nil
+		})
+	}
+	_ = group.Wait()
+}
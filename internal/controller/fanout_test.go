@@ -0,0 +1,39 @@
+package controller
+
+import (
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFanOut(t *testing.T) {
+	var (
+		mu         sync.Mutex
+		called     []string
+		running    atomic.Int32
+		maxRunning atomic.Int32
+	)
+	namespaces := []string{"a", "b", "c", "d", "e", "f"}
+
+	FanOut(2, namespaces, func(namespace string) {
+		n := running.Add(1)
+		defer running.Add(-1)
+		for {
+			current := maxRunning.Load()
+			if n <= current || maxRunning.CompareAndSwap(current, n) {
+				break
+			}
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		called = append(called, namespace)
+	})
+
+	slices.Sort(called)
+	assert.Equal(t, namespaces, called)
+	assert.LessOrEqual(t, maxRunning.Load(), int32(2))
+}
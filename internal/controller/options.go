@@ -0,0 +1,32 @@
+// Package controller contains the settings shared by all controllers of replik8or.
+package controller
+
+import (
+	"golang.org/x/time/rate"
+	"k8s.io/client-go/util/workqueue"
+	ctrlcontroller "sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/c0deltin/replik8or/internal/config"
+)
+
+// Options returns the controller options configured by cfg. Every controller requires its own options, as the rate
+// limiter keeps track of the failures per request.
+func Options(cfg *config.Config) ctrlcontroller.Options {
+	return TypedOptions[reconcile.Request](cfg)
+}
+
+// TypedOptions returns the Options for controllers with a custom request type.
+func TypedOptions[request comparable](cfg *config.Config) ctrlcontroller.TypedOptions[request] {
+	return ctrlcontroller.TypedOptions[request]{
+		MaxConcurrentReconciles: cfg.MaxConcurrentReconciles,
+		RateLimiter: workqueue.NewTypedMaxOfRateLimiter(
+			workqueue.NewTypedItemExponentialFailureRateLimiter[request](
+				cfg.RateLimiterBaseDelay,
+				cfg.RateLimiterMaxDelay,
+			),
+			// overall retry limit of the default controller rate limiter
+			&workqueue.TypedBucketRateLimiter[request]{Limiter: rate.NewLimiter(rate.Limit(10), 100)},
+		),
+	}
+}
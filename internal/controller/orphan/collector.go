@@ -0,0 +1,222 @@
+package orphan
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/c0deltin/replik8or/internal/config"
+	"github.com/c0deltin/replik8or/internal/replicator"
+)
+
+// Reasons a replica is orphaned, used as metric label.
+const (
+	reasonSourceMissing = "source_missing"
+	reasonNotTargeted   = "not_targeted"
+)
+
+// Orphan is a replica whose source no longer exists or no longer targets its namespace. The source of a replica
+// whose source is missing only carries its name and namespace.
+type Orphan[T client.Object] struct {
+	Replica client.Object
+	Source  T
+	Reason  string
+}
+
+// Collector periodically lists all replicas and collects the ones whose source no longer exists or no longer targets
+// their namespace, e.g. because the source was force-deleted while the operator was down.
+type Collector[T client.Object] struct {
+	client   client.Client
+	config   *config.Config
+	recorder record.EventRecorder
+
+	emptyObjectFn     func() T
+	emptyObjectListFn func() client.ObjectList
+
+	replicator *replicator.Replicator[T]
+}
+
+func NewCollector[T client.Object](
+	client client.Client,
+	config *config.Config,
+	recorder record.EventRecorder,
+	emptyObjectFn func() T,
+	emptyObjectListFn func() client.ObjectList,
+) *Collector[T] {
+	return &Collector[T]{
+		client:            client,
+		config:            config,
+		recorder:          recorder,
+		emptyObjectFn:     emptyObjectFn,
+		emptyObjectListFn: emptyObjectListFn,
+		replicator:        replicator.New[T](client, client, config, recorder),
+	}
+}
+
+// Start runs the collection in the configured interval until ctx is done.
+func (c *Collector[T]) Start(ctx context.Context) error {
+	lgr := log.FromContext(ctx).WithName("orphan").WithValues("kind", replicator.Kind(c.emptyObjectFn()))
+
+	ticker := time.NewTicker(c.config.OrphanCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			orphans, err := c.collect(ctx)
+			if err != nil {
+				lgr.Error(err, "collecting orphaned replicas")
+			}
+			if orphans > 0 {
+				lgr.Info("found orphaned replicas", "count", orphans, "deleted", c.config.OrphanDelete)
+			}
+		}
+	}
+}
+
+// NeedLeaderElection makes sure only the leader collects orphaned replicas.
+func (c *Collector[T]) NeedLeaderElection() bool {
+	return true
+}
+
+// collect collects the orphaned replicas and returns their number. Orphans are deleted if enabled by configuration,
+// otherwise they are only reported. Failed deletions don't stop the collection of other orphans.
+func (c *Collector[T]) collect(ctx context.Context) (int, error) {
+	orphans, err := c.Find(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	var (
+		errs     []error
+		byReason = map[string]int{reasonSourceMissing: 0, reasonNotTargeted: 0}
+	)
+	for _, orphan := range orphans {
+		byReason[orphan.Reason]++
+		if err := c.handle(ctx, orphan); err != nil {
+			errs = append(errs, err)
+		}
+		if orphan.Reason == reasonSourceMissing {
+			c.replicator.Forget(client.ObjectKeyFromObject(orphan.Source))
+		}
+	}
+
+	kind := replicator.Kind(c.emptyObjectFn())
+	for reason, count := range byReason {
+		orphanedReplicas.WithLabelValues(kind, reason).Set(float64(count))
+	}
+	return len(orphans), errors.Join(errs...)
+}
+
+// Find lists all replicas and returns the orphaned ones. Replicas managed by a policy are cleaned up by the policy
+// controllers and therefore never orphaned. Replicas whose source can not be read are skipped.
+func (c *Collector[T]) Find(ctx context.Context) ([]Orphan[T], error) {
+	var replicaList = c.emptyObjectListFn()
+	if err := c.client.List(ctx, replicaList, client.HasLabels{
+		replicator.SourceNamespaceLabel,
+		replicator.SourceNameLabel,
+	}); err != nil {
+		return nil, err
+	}
+
+	replicas, err := meta.ExtractList(replicaList)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		orphans []Orphan[T]
+		sources = map[client.ObjectKey]T{}
+		missing = map[client.ObjectKey]bool{}
+		failed  = map[client.ObjectKey]bool{}
+	)
+	for _, object := range replicas {
+		replica := object.(client.Object)
+		if replicator.IsPolicyReplica(replica) || !replica.GetDeletionTimestamp().IsZero() {
+			continue
+		}
+
+		key := client.ObjectKey{
+			Namespace: replica.GetLabels()[replicator.SourceNamespaceLabel],
+			Name:      replica.GetLabels()[replicator.SourceNameLabel],
+		}
+		if failed[key] {
+			continue
+		}
+
+		source, ok := sources[key]
+		if !ok && !missing[key] {
+			source = c.emptyObjectFn()
+			if err := c.client.Get(ctx, key, source); err != nil {
+				if !apierrors.IsNotFound(err) {
+					// e.g. the namespace of the source is not watched, which must not fail the collection of others
+					log.FromContext(ctx).Error(err, "skipping replicas of unreadable source", "source", key)
+					failed[key] = true
+					continue
+				}
+				missing[key] = true
+			} else {
+				sources[key] = source
+			}
+		}
+
+		if missing[key] {
+			source = c.emptyObjectFn()
+			source.SetName(key.Name)
+			source.SetNamespace(key.Namespace)
+			orphans = append(orphans, Orphan[T]{Replica: replica, Source: source, Reason: reasonSourceMissing})
+			continue
+		}
+
+		orphaned, err := c.IsOrphaned(ctx, replica, source)
+		if err != nil {
+			return nil, err
+		}
+		if orphaned {
+			orphans = append(orphans, Orphan[T]{Replica: replica, Source: source, Reason: reasonNotTargeted})
+		}
+	}
+	return orphans, nil
+}
+
+// IsOrphaned reports whether source no longer targets the namespace of replica. Sources being deleted are left to
+// the source controllers, which remove their replicas before the finalizer.
+func (c *Collector[T]) IsOrphaned(ctx context.Context, replica client.Object, source T) (bool, error) {
+	if !source.GetDeletionTimestamp().IsZero() {
+		return false, nil
+	}
+	if !replicator.HasAnnotations(source, replicator.ReplicationAllowedAnnotation) ||
+		slices.Contains(c.config.DisallowedSourceNamespaces, source.GetNamespace()) {
+		return true, nil
+	}
+
+	targeted, err := c.replicator.IsTargetNamespace(ctx, source, replica.GetNamespace())
+	return !targeted, err
+}
+
+// handle deletes the orphaned replica or reports it by an event, if the deletion is disabled by configuration.
+func (c *Collector[T]) handle(ctx context.Context, orphan Orphan[T]) error {
+	if c.config.OrphanDelete {
+		return client.IgnoreNotFound(c.replicator.Delete(ctx, orphan.Source, orphan.Replica))
+	}
+
+	if orphan.Reason == reasonSourceMissing {
+		c.recorder.Eventf(orphan.Replica, corev1.EventTypeWarning, replicator.EventReasonOrphaned,
+			"Replica is orphaned, source %s does not exist", client.ObjectKeyFromObject(orphan.Source))
+		return nil
+	}
+	c.recorder.Eventf(orphan.Replica, corev1.EventTypeWarning, replicator.EventReasonOrphaned,
+		"Replica is orphaned, source %s no longer targets namespace %s",
+		client.ObjectKeyFromObject(orphan.Source), orphan.Replica.GetNamespace())
+	return nil
+}
@@ -0,0 +1,158 @@
+package orphan
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	"github.com/c0deltin/replik8or/internal/config"
+	"github.com/c0deltin/replik8or/internal/replicator"
+)
+
+func TestCollector_collect(t *testing.T) {
+	replicated := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "replicated",
+			Namespace: "source-namespace",
+			Annotations: map[string]string{
+				replicator.ReplicationAllowedAnnotation: "true",
+				replicator.DesiredNamespacesAnnotation:  "targeted",
+			},
+		},
+	}
+	notReplicated := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "not-replicated", Namespace: "source-namespace"}}
+
+	targeted := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "replicated",
+			Namespace: "targeted",
+			Labels: map[string]string{
+				replicator.SourceNamespaceLabel: "source-namespace",
+				replicator.SourceNameLabel:      "replicated",
+			},
+		},
+	}
+	notTargeted := targeted.DeepCopy()
+	notTargeted.Namespace = "not-targeted"
+	ofNotReplicated := targeted.DeepCopy()
+	ofNotReplicated.Name = "not-replicated"
+	ofNotReplicated.Labels[replicator.SourceNameLabel] = "not-replicated"
+	ofDeleted := targeted.DeepCopy()
+	ofDeleted.Name = "deleted"
+	ofDeleted.Labels[replicator.SourceNameLabel] = "deleted"
+	ofPolicy := ofDeleted.DeepCopy()
+	ofPolicy.Namespace = "policy"
+	ofPolicy.Labels[replicator.PolicyLabel] = "policy"
+
+	objects := func() []client.Object {
+		return []client.Object{
+			replicated.DeepCopy(),
+			notReplicated.DeepCopy(),
+			targeted.DeepCopy(),
+			notTargeted.DeepCopy(),
+			ofNotReplicated.DeepCopy(),
+			ofDeleted.DeepCopy(),
+			ofPolicy.DeepCopy(),
+		}
+	}
+
+	t.Run("report orphans", func(t *testing.T) {
+		fakeClient := fake.NewClientBuilder().WithObjects(objects()...).Build()
+		recorder := record.NewFakeRecorder(3)
+		c := NewCollector[*corev1.ConfigMap](
+			fakeClient,
+			&config.Config{},
+			recorder,
+			replicator.EmptyConfigMap,
+			replicator.EmptyConfigMapList,
+		)
+
+		orphans, err := c.collect(t.Context())
+
+		assert.NoError(t, err)
+		assert.Equal(t, 3, orphans)
+		assert.InDelta(t, 1, testutil.ToFloat64(orphanedReplicas.WithLabelValues("ConfigMap", reasonSourceMissing)), 0)
+		assert.InDelta(t, 2, testutil.ToFloat64(orphanedReplicas.WithLabelValues("ConfigMap", reasonNotTargeted)), 0)
+		for range orphans {
+			assert.Contains(t, <-recorder.Events, "Warning Orphaned Replica is orphaned")
+		}
+
+		var replicaList corev1.ConfigMapList
+		assert.NoError(t, fakeClient.List(t.Context(), &replicaList))
+		assert.Len(t, replicaList.Items, 7)
+	})
+
+	t.Run("delete orphans", func(t *testing.T) {
+		fakeClient := fake.NewClientBuilder().WithObjects(objects()...).Build()
+		c := NewCollector[*corev1.ConfigMap](
+			fakeClient,
+			&config.Config{OrphanDelete: true},
+			record.NewFakeRecorder(3),
+			replicator.EmptyConfigMap,
+			replicator.EmptyConfigMapList,
+		)
+
+		orphans, err := c.collect(t.Context())
+
+		assert.NoError(t, err)
+		assert.Equal(t, 3, orphans)
+
+		for _, orphan := range []client.ObjectKey{
+			{Namespace: "not-targeted", Name: "replicated"},
+			{Namespace: "targeted", Name: "not-replicated"},
+			{Namespace: "targeted", Name: "deleted"},
+		} {
+			err := fakeClient.Get(t.Context(), orphan, &corev1.ConfigMap{})
+			assert.True(t, apierrors.IsNotFound(err), orphan.String())
+		}
+		for _, kept := range []client.ObjectKey{
+			{Namespace: "targeted", Name: "replicated"},
+			{Namespace: "policy", Name: "deleted"},
+		} {
+			assert.NoError(t, fakeClient.Get(t.Context(), kept, &corev1.ConfigMap{}), kept.String())
+		}
+	})
+
+	t.Run("skip unreadable sources", func(t *testing.T) {
+		// the source of the replica is located in a namespace which is not watched
+		unwatched := ofDeleted.DeepCopy()
+		unwatched.Name = "unwatched"
+		unwatched.Labels[replicator.SourceNamespaceLabel] = "unwatched"
+		unwatched.Labels[replicator.SourceNameLabel] = "unwatched"
+
+		fakeClient := fake.NewClientBuilder().
+			WithObjects(append(objects(), unwatched)...).
+			WithInterceptorFuncs(interceptor.Funcs{
+				Get: func(ctx context.Context, c client.WithWatch, key client.ObjectKey, obj client.Object,
+					opts ...client.GetOption) error {
+					if key.Namespace == "unwatched" {
+						return errors.New("unknown namespace for the cache")
+					}
+					return c.Get(ctx, key, obj, opts...)
+				},
+			}).
+			Build()
+		c := NewCollector[*corev1.ConfigMap](
+			fakeClient,
+			&config.Config{},
+			record.NewFakeRecorder(3),
+			replicator.EmptyConfigMap,
+			replicator.EmptyConfigMapList,
+		)
+
+		orphans, err := c.collect(t.Context())
+
+		assert.NoError(t, err)
+		assert.Equal(t, 3, orphans)
+	})
+}
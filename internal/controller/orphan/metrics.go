@@ -0,0 +1,15 @@
+package orphan
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+var orphanedReplicas = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "replik8or_orphaned_replicas",
+	Help: "Number of replicas whose source no longer exists or no longer targets their namespace.",
+}, []string{"kind", "reason"})
+
+func init() {
+	metrics.Registry.MustRegister(orphanedReplicas)
+}
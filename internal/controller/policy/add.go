@@ -0,0 +1,141 @@
+package policy
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	ctrlsource "sigs.k8s.io/controller-runtime/pkg/source"
+
+	"github.com/c0deltin/replik8or/api/v1alpha1"
+	"github.com/c0deltin/replik8or/internal/controller"
+	"github.com/c0deltin/replik8or/internal/namespaces"
+	"github.com/c0deltin/replik8or/internal/replicator"
+)
+
+const sourceIndexField = "spec.source"
+
+func (r *Reconciler[P]) SetupWithManager(mgr manager.Manager) error {
+	if err := r.setSourceIndexer(mgr); err != nil {
+		return err
+	}
+
+	b := builder.ControllerManagedBy(mgr).
+		Named(r.name).
+		For(r.emptyPolicyFn()).
+		Watches(
+			replicator.EmptyConfigMap(),
+			handler.EnqueueRequestsFromMapFunc(r.mapObjectsToPolicies(v1alpha1.SourceKindConfigMap)),
+		).
+		Watches(
+			replicator.EmptySecret(),
+			handler.EnqueueRequestsFromMapFunc(r.mapObjectsToPolicies(v1alpha1.SourceKindSecret)),
+		).
+		WatchesRawSource(ctrlsource.Channel(r.requeue, &handler.EnqueueRequestForObject{}))
+
+	// namespaces are not watched when the operator is restricted to a fixed set of them
+	if len(r.config.WatchNamespaces) == 0 {
+		b = b.Watches(
+			namespaces.Metadata(),
+			handler.EnqueueRequestsFromMapFunc(r.mapNamespacesToPolicies),
+		)
+	}
+
+	return b.
+		WithOptions(controller.Options(r.config)).
+		WithLogConstructor(func(r *reconcile.Request) logr.Logger {
+			return ctrl.Log.WithName("replik8or")
+		}).
+		Complete(r)
+}
+
+func (r *Reconciler[P]) setSourceIndexer(mgr manager.Manager) error {
+	return mgr.GetFieldIndexer().IndexField(
+		context.Background(),
+		r.emptyPolicyFn(),
+		sourceIndexField,
+		func(object client.Object) []string {
+			policy := object.(P)
+			return []string{sourceIndexValue(policy.GetSourceKind(), policy.GetSourceKey())}
+		},
+	)
+}
+
+func sourceIndexValue(kind v1alpha1.SourceKind, key types.NamespacedName) string {
+	return string(kind) + "/" + key.String()
+}
+
+// mapObjectsToPolicies enqueues the policies referencing the object as source as well as the policy managing the
+// object as replica. Events of replicas also enqueue the policies referencing their source, e.g. to take over the
+// namespace of a deleted replica which was managed with a higher precedence.
+func (r *Reconciler[P]) mapObjectsToPolicies(kind v1alpha1.SourceKind) handler.MapFunc {
+	return func(ctx context.Context, object client.Object) []reconcile.Request {
+		var requests []reconcile.Request
+
+		labels := object.GetLabels()
+		if name, ok := labels[r.policyLabel]; ok {
+			key := client.ObjectKey{Name: name}
+			if r.policyLabel == replicator.PolicyLabel {
+				key.Namespace = labels[replicator.SourceNamespaceLabel]
+			}
+			requests = append(requests, reconcile.Request{NamespacedName: key})
+		}
+
+		sources := []types.NamespacedName{client.ObjectKeyFromObject(object)}
+		if replicator.HasLabels(object, replicator.SourceNamespaceLabel, replicator.SourceNameLabel) {
+			sources = append(sources, types.NamespacedName{
+				Namespace: labels[replicator.SourceNamespaceLabel],
+				Name:      labels[replicator.SourceNameLabel],
+			})
+		}
+
+		for _, source := range sources {
+			requests = append(requests, r.policiesReferencing(ctx, kind, source)...)
+		}
+		return requests
+	}
+}
+
+// policiesReferencing returns requests for all policies referencing the source.
+func (r *Reconciler[P]) policiesReferencing(
+	ctx context.Context,
+	kind v1alpha1.SourceKind,
+	source types.NamespacedName,
+) []reconcile.Request {
+	var policyList = r.emptyPolicyListFn()
+	if err := r.client.List(ctx, policyList, client.MatchingFields{
+		sourceIndexField: sourceIndexValue(kind, source),
+	}); err != nil {
+		return nil
+	}
+	return requestsFor(policyList)
+}
+
+// mapNamespacesToPolicies enqueues all policies as any of them might target the namespace.
+func (r *Reconciler[P]) mapNamespacesToPolicies(ctx context.Context, _ client.Object) []reconcile.Request {
+	var policyList = r.emptyPolicyListFn()
+	if err := r.client.List(ctx, policyList); err != nil {
+		return nil
+	}
+	return requestsFor(policyList)
+}
+
+func requestsFor(policyList client.ObjectList) []reconcile.Request {
+	policies, err := meta.ExtractList(policyList)
+	if err != nil {
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for _, policy := range policies {
+		requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(policy.(client.Object))})
+	}
+	return requests
+}
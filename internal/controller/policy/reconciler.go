@@ -0,0 +1,467 @@
+package policy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/c0deltin/replik8or/api/v1alpha1"
+	"github.com/c0deltin/replik8or/internal/config"
+	"github.com/c0deltin/replik8or/internal/controller"
+	"github.com/c0deltin/replik8or/internal/namespaces"
+	"github.com/c0deltin/replik8or/internal/replicator"
+)
+
+const (
+	policyFinalizer = "replik8or.c0deltin.dev/policy"
+
+	reasonReplicated        = "Replicated"
+	reasonSourceNotFound    = "SourceNotFound"
+	reasonSourceNotAllowed  = "SourceNotAllowed"
+	reasonReplicationFailed = "ReplicationFailed"
+)
+
+var (
+	errSourceNotFound   = errors.New("source not found")
+	errSourceNotAllowed = errors.New("source namespace is disallowed")
+)
+
+// Policy is implemented by ReplicationPolicy and ClusterReplicationPolicy.
+type Policy interface {
+	client.Object
+	GetSourceKey() types.NamespacedName
+	GetSourceKind() v1alpha1.SourceKind
+	GetReplicationSpec() *v1alpha1.ReplicationSpec
+	GetReplicationStatus() *v1alpha1.ReplicationPolicyStatus
+}
+
+// Reconciler replicates the source referenced by a policy into the targeted namespaces.
+type Reconciler[P Policy] struct {
+	name       string
+	client     client.Client
+	namespaces *namespaces.Index
+	config     *config.Config
+	recorder   record.EventRecorder
+	// apiReader reads the sources, which are cached without their data unless they are annotated or labeled
+	apiReader client.Reader
+
+	emptyPolicyFn     func() P
+	emptyPolicyListFn func() client.ObjectList
+	// policyLabel references the policy on its replicas
+	policyLabel string
+
+	configMaps *replicator.Replicator[*corev1.ConfigMap]
+	secrets    *replicator.Replicator[*corev1.Secret]
+	requeue    chan event.GenericEvent
+}
+
+// NewReconciler returns a Reconciler for ReplicationPolicy resources.
+func NewReconciler(
+	c client.Client,
+	apiReader client.Reader,
+	namespaces *namespaces.Index,
+	config *config.Config,
+	recorder record.EventRecorder,
+) *Reconciler[*v1alpha1.ReplicationPolicy] {
+	return newReconciler(c, apiReader, namespaces, config, recorder, "replication-policy", replicator.PolicyLabel,
+		func() *v1alpha1.ReplicationPolicy { return &v1alpha1.ReplicationPolicy{} },
+		func() client.ObjectList { return &v1alpha1.ReplicationPolicyList{} },
+	)
+}
+
+// NewClusterReconciler returns a Reconciler for ClusterReplicationPolicy resources.
+func NewClusterReconciler(
+	c client.Client,
+	apiReader client.Reader,
+	namespaces *namespaces.Index,
+	config *config.Config,
+	recorder record.EventRecorder,
+) *Reconciler[*v1alpha1.ClusterReplicationPolicy] {
+	return newReconciler(c, apiReader, namespaces, config, recorder, "cluster-replication-policy", replicator.ClusterPolicyLabel,
+		func() *v1alpha1.ClusterReplicationPolicy { return &v1alpha1.ClusterReplicationPolicy{} },
+		func() client.ObjectList { return &v1alpha1.ClusterReplicationPolicyList{} },
+	)
+}
+
+func newReconciler[P Policy](
+	client client.Client,
+	apiReader client.Reader,
+	namespaces *namespaces.Index,
+	config *config.Config,
+	recorder record.EventRecorder,
+	name, policyLabel string,
+	emptyPolicyFn func() P,
+	emptyPolicyListFn func() client.ObjectList,
+) *Reconciler[P] {
+	return &Reconciler[P]{
+		name:              name,
+		client:            client,
+		apiReader:         apiReader,
+		namespaces:        namespaces,
+		config:            config,
+		recorder:          recorder,
+		emptyPolicyFn:     emptyPolicyFn,
+		emptyPolicyListFn: emptyPolicyListFn,
+		policyLabel:       policyLabel,
+		configMaps:        replicator.New[*corev1.ConfigMap](client, apiReader, config, recorder),
+		secrets:           replicator.New[*corev1.Secret](client, apiReader, config, recorder),
+		requeue:           make(chan event.GenericEvent),
+	}
+}
+
+// Requeue returns a channel to trigger the reconciliation of a policy from outside the controller.
+func (r *Reconciler[P]) Requeue() chan<- event.GenericEvent {
+	return r.requeue
+}
+
+func (r *Reconciler[P]) Reconcile(ctx context.Context, req reconcile.Request) (reconcile.Result, error) {
+	var policy = r.emptyPolicyFn()
+	if err := r.client.Get(ctx, req.NamespacedName, policy); err != nil {
+		return reconcile.Result{}, client.IgnoreNotFound(err)
+	}
+
+	if !policy.GetDeletionTimestamp().IsZero() {
+		return r.finalizeAndDelete(ctx, policy)
+	}
+
+	if controllerutil.AddFinalizer(policy, policyFinalizer) {
+		if err := r.client.Update(ctx, policy); err != nil {
+			return reconcile.Result{}, err
+		}
+		return reconcile.Result{RequeueAfter: time.Second}, nil
+	}
+
+	targetNamespaces, err := r.targetNamespaces(ctx, policy)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+
+	var (
+		res          result
+		replicateErr error
+	)
+	switch {
+	case slices.Contains(r.config.DisallowedSourceNamespaces, policy.GetSourceKey().Namespace):
+		replicateErr = errSourceNotAllowed
+	case policy.GetSourceKind() == v1alpha1.SourceKindConfigMap:
+		res, replicateErr = replicate(ctx, r.configMaps, r.apiReader, policy, r.replicaLabels(policy), targetNamespaces,
+			r.config.FanoutConcurrency, replicator.EmptyConfigMap)
+	case policy.GetSourceKind() == v1alpha1.SourceKindSecret:
+		res, replicateErr = replicate(ctx, r.secrets, r.apiReader, policy, r.replicaLabels(policy), targetNamespaces,
+			r.config.FanoutConcurrency, replicator.EmptySecret)
+	default:
+		replicateErr = fmt.Errorf("kind %q not supported", policy.GetSourceKind())
+	}
+	if replicateErr != nil && !errors.Is(replicateErr, errSourceNotFound) &&
+		!errors.Is(replicateErr, errSourceNotAllowed) {
+		return reconcile.Result{}, replicateErr
+	}
+
+	// replicas of a missing or disallowed source are released like the ones of namespaces which are no longer targeted
+	if err := r.prune(ctx, policy, res); err != nil {
+		return reconcile.Result{}, err
+	}
+
+	if err := r.updateStatus(ctx, policy, res, replicateErr); err != nil {
+		return reconcile.Result{}, err
+	}
+
+	// conflicts are reported by status only as retrying will not resolve them
+	var errs []error
+	for _, failure := range res.failures {
+		if !errors.Is(failure, replicator.ErrConflict) {
+			errs = append(errs, failure)
+		}
+	}
+	return reconcile.Result{}, errors.Join(errs...)
+}
+
+// result is the outcome of the replication of a policy.
+type result struct {
+	// replicatedTo are the namespaces the source was replicated to.
+	replicatedTo []string
+	// overridden are the namespaces whose replica is managed by a policy of higher precedence.
+	overridden []string
+	// failures are the errors per namespace.
+	failures map[string]error
+}
+
+// replicate writes the replicas of the source referenced by policy into the target namespaces.
+func replicate[T client.Object](
+	ctx context.Context,
+	rep *replicator.Replicator[T],
+	reader client.Reader,
+	policy Policy,
+	replicaLabels map[string]string,
+	targetNamespaces []string,
+	concurrency int,
+	emptyObjectFn func() T,
+) (result, error) {
+	var source = emptyObjectFn()
+	if err := reader.Get(ctx, policy.GetSourceKey(), source); err != nil {
+		if apierrors.IsNotFound(err) {
+			return result{}, errSourceNotFound
+		}
+		return result{}, err
+	}
+
+	opts := []replicator.Option{replicator.WithLabels(replicaLabels)}
+	if keys := policy.GetReplicationSpec().Keys; keys != nil {
+		opts = append(opts, replicator.WithKeyFilter(keys.Include, keys.Exclude))
+	}
+
+	var (
+		mu  sync.Mutex
+		res = result{failures: map[string]error{}}
+	)
+	controller.FanOut(concurrency, targetNamespaces, func(targetNamespace string) {
+		var replica = emptyObjectFn()
+		replica.SetName(source.GetName())
+		replica.SetNamespace(targetNamespace)
+
+		err := rep.CreateOrUpdate(ctx, source, replica, opts...)
+
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case errors.Is(err, replicator.ErrNamespaceTerminating), errors.Is(err, replicator.ErrNamespaceNotFound):
+			// the namespace is no longer targeted once it was removed, or targeted again once it was created
+		case errors.Is(err, replicator.ErrOverridden):
+			res.overridden = append(res.overridden, targetNamespace)
+		case err != nil:
+			res.failures[targetNamespace] = err
+		default:
+			res.replicatedTo = append(res.replicatedTo, targetNamespace)
+		}
+	})
+
+	// the namespaces are replicated in parallel, sorting them keeps the status stable
+	slices.Sort(res.replicatedTo)
+	slices.Sort(res.overridden)
+	return res, nil
+}
+
+// replicaLabels returns the labels referencing policy on its replicas.
+func (r *Reconciler[P]) replicaLabels(policy P) map[string]string {
+	return map[string]string{r.policyLabel: policy.GetName()}
+}
+
+// replicaSelector returns the labels selecting all replicas of policy. Replicas of a ReplicationPolicy are
+// additionally selected by the namespace of the policy, which is also the namespace of their source.
+func (r *Reconciler[P]) replicaSelector(policy P) client.MatchingLabels {
+	selector := client.MatchingLabels(r.replicaLabels(policy))
+	if policy.GetNamespace() != "" {
+		selector[replicator.SourceNamespaceLabel] = policy.GetNamespace()
+	}
+	return selector
+}
+
+// targetNamespaces returns the names of the active namespaces targeted by policy. The namespace of the source and
+// the namespaces disallowed by configuration are never targeted. If enabled by configuration, namespaced policies
+// only target the namespaces the ServiceAccount of the policy is authorized to write to, just like annotated sources.
+func (r *Reconciler[P]) targetNamespaces(ctx context.Context, policy P) ([]string, error) {
+	var (
+		targets = map[string]struct{}{}
+		spec    = policy.GetReplicationSpec()
+	)
+
+	for _, name := range spec.Targets.Namespaces {
+		namespace, ok, err := r.namespaces.Get(name)
+		if err != nil {
+			return nil, err
+		}
+		if ok && namespace.Phase == corev1.NamespaceActive {
+			targets[name] = struct{}{}
+		}
+	}
+
+	if spec.Targets.NamespaceSelector != nil {
+		selector, err := metav1.LabelSelectorAsSelector(spec.Targets.NamespaceSelector)
+		if err != nil {
+			return nil, err
+		}
+
+		namespaceList, err := r.namespaces.List(selector)
+		if err != nil {
+			return nil, err
+		}
+		for _, namespace := range namespaceList {
+			if namespace.Phase == corev1.NamespaceActive {
+				targets[namespace.Name] = struct{}{}
+			}
+		}
+	}
+
+	delete(targets, policy.GetSourceKey().Namespace)
+	for _, namespace := range r.config.DisallowedNamespaces {
+		delete(targets, namespace)
+	}
+	names := slices.Sorted(maps.Keys(targets))
+
+	// a ClusterReplicationPolicy can only be created by cluster administrators and is therefore not authorized
+	if !r.config.AuthorizeTargets || policy.GetNamespace() == "" {
+		return names, nil
+	}
+	switch policy.GetSourceKind() {
+	case v1alpha1.SourceKindSecret:
+		return r.secrets.AuthorizedNamespaces(ctx, policy, names)
+	default:
+		return r.configMaps.AuthorizedNamespaces(ctx, policy, names)
+	}
+}
+
+// prune releases all replicas of policy which are neither replicated nor failed in this reconciliation.
+func (r *Reconciler[P]) prune(ctx context.Context, policy P, res result) error {
+	keep := func(replica client.Object) bool {
+		if v1alpha1.SourceKind(replicator.Kind(replica)) != policy.GetSourceKind() ||
+			!replicator.IsReplicaOf(replica, sourceReference(policy)) {
+			return false
+		}
+		_, failed := res.failures[replica.GetNamespace()]
+		return failed || slices.Contains(res.replicatedTo, replica.GetNamespace())
+	}
+
+	return r.releaseReplicas(ctx, policy, keep)
+}
+
+// sourceReference returns an object carrying the key of the source referenced by policy.
+func sourceReference(policy Policy) client.Object {
+	key := policy.GetSourceKey()
+	return &metav1.PartialObjectMetadata{ObjectMeta: metav1.ObjectMeta{Namespace: key.Namespace, Name: key.Name}}
+}
+
+func (r *Reconciler[P]) finalizeAndDelete(ctx context.Context, policy P) (reconcile.Result, error) {
+	if err := r.releaseReplicas(ctx, policy, func(client.Object) bool { return false }); err != nil {
+		return reconcile.Result{}, err
+	}
+
+	if controllerutil.RemoveFinalizer(policy, policyFinalizer) {
+		if err := r.client.Update(ctx, policy); err != nil {
+			return reconcile.Result{}, err
+		}
+	}
+
+	return reconcile.Result{}, nil
+}
+
+// releaseReplicas releases all replicas of policy keep returns false for according to the deletion policy.
+func (r *Reconciler[P]) releaseReplicas(ctx context.Context, policy P, keep func(client.Object) bool) error {
+	for _, replicaList := range []client.ObjectList{replicator.EmptyConfigMapList(), replicator.EmptySecretList()} {
+		if err := r.client.List(ctx, replicaList, r.replicaSelector(policy)); err != nil {
+			return err
+		}
+
+		replicas, err := meta.ExtractList(replicaList)
+		if err != nil {
+			return err
+		}
+
+		for _, object := range replicas {
+			replica := object.(client.Object)
+			if keep(replica) {
+				continue
+			}
+			if err := r.release(ctx, policy, replica); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// release deletes the replica or, if the policy retains replicas, removes the labels marking it as replica.
+func (r *Reconciler[P]) release(ctx context.Context, policy P, replica client.Object) error {
+	if policy.GetReplicationSpec().DeletionPolicy == v1alpha1.DeletionPolicyRetain {
+		patch := client.MergeFrom(replica.DeepCopyObject().(client.Object))
+
+		labels := maps.Clone(replica.GetLabels())
+		delete(labels, replicator.SourceNamespaceLabel)
+		delete(labels, replicator.SourceNameLabel)
+		delete(labels, r.policyLabel)
+		replica.SetLabels(labels)
+
+		var opts []client.PatchOption
+		if r.config.DryRun {
+			opts = append(opts, client.DryRunAll)
+		}
+		return r.client.Patch(ctx, replica, patch, opts...)
+	}
+
+	switch replica.(type) {
+	case *corev1.Secret:
+		return r.secrets.Delete(ctx, policy, replica)
+	default:
+		return r.configMaps.Delete(ctx, policy, replica)
+	}
+}
+
+// updateStatus writes the outcome of the replication to the status of policy if it changed.
+func (r *Reconciler[P]) updateStatus(ctx context.Context, policy P, res result, err error) error {
+	condition := metav1.Condition{
+		Type:               v1alpha1.ConditionTypeReady,
+		Status:             metav1.ConditionTrue,
+		Reason:             reasonReplicated,
+		Message:            fmt.Sprintf("Replicated to %d namespaces", len(res.replicatedTo)),
+		ObservedGeneration: policy.GetGeneration(),
+	}
+	if len(res.overridden) > 0 {
+		condition.Message += fmt.Sprintf(", overridden by a policy of higher precedence in %s",
+			strings.Join(res.overridden, ","))
+	}
+	switch {
+	case errors.Is(err, errSourceNotFound):
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = reasonSourceNotFound
+		condition.Message = fmt.Sprintf("%s %s not found", policy.GetSourceKind(), policy.GetSourceKey())
+	case errors.Is(err, errSourceNotAllowed):
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = reasonSourceNotAllowed
+		condition.Message = fmt.Sprintf("%s %s is located in a disallowed source namespace", policy.GetSourceKind(),
+			policy.GetSourceKey())
+	case len(res.failures) > 0:
+		var messages []string
+		for _, namespace := range slices.Sorted(maps.Keys(res.failures)) {
+			messages = append(messages, fmt.Sprintf("%s: %v", namespace, res.failures[namespace]))
+		}
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = reasonReplicationFailed
+		condition.Message = strings.Join(messages, "; ")
+	}
+
+	status := policy.GetReplicationStatus()
+	changed := meta.SetStatusCondition(&status.Conditions, condition)
+	if !slices.Equal(status.ReplicatedTo, res.replicatedTo) {
+		status.ReplicatedTo = res.replicatedTo
+		changed = true
+	}
+	if status.ObservedGeneration != policy.GetGeneration() {
+		status.ObservedGeneration = policy.GetGeneration()
+		changed = true
+	}
+	if !changed {
+		return nil
+	}
+
+	if condition.Status == metav1.ConditionFalse {
+		r.recorder.Event(policy, corev1.EventTypeWarning, condition.Reason, condition.Message)
+	} else {
+		r.recorder.Event(policy, corev1.EventTypeNormal, condition.Reason, condition.Message)
+	}
+	return r.client.Status().Update(ctx, policy)
+}
@@ -0,0 +1,354 @@
+package policy
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/c0deltin/replik8or/api/v1alpha1"
+	"github.com/c0deltin/replik8or/internal/config"
+	"github.com/c0deltin/replik8or/internal/namespaces"
+	"github.com/c0deltin/replik8or/internal/replicator"
+)
+
+func newFakeClient(t *testing.T, objects ...client.Object) client.Client {
+	scheme := runtime.NewScheme()
+	assert.NoError(t, corev1.AddToScheme(scheme))
+	assert.NoError(t, v1alpha1.AddToScheme(scheme))
+
+	return fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(objects...).
+		WithStatusSubresource(&v1alpha1.ReplicationPolicy{}, &v1alpha1.ClusterReplicationPolicy{}).
+		Build()
+}
+
+func namespace(name string, labels map[string]string) *corev1.Namespace {
+	return &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels}}
+}
+
+// newIndex returns a namespace index of the namespaces among objects.
+func newIndex(objects ...client.Object) *namespaces.Index {
+	index := namespaces.NewIndex()
+	for _, object := range objects {
+		if _, ok := object.(*corev1.Namespace); ok {
+			index.Set(namespaces.FromObject(object))
+		}
+	}
+	return index
+}
+
+func TestReconciler_Reconcile(t *testing.T) {
+	source := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "source-name", Namespace: "default"},
+		Data:       map[string]string{"foo": "bar", "lorem": "ipsum"},
+	}
+	policy := &v1alpha1.ReplicationPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "policy", Namespace: "default"},
+		Spec: v1alpha1.ReplicationPolicySpec{
+			Source: v1alpha1.SourceReference{Kind: v1alpha1.SourceKindConfigMap, Name: "source-name"},
+			ReplicationSpec: v1alpha1.ReplicationSpec{
+				Targets: v1alpha1.Targets{
+					Namespaces:        []string{"testing", "unknown", "disallowed"},
+					NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"tier": "app"}},
+				},
+				Keys:           &v1alpha1.KeyFilter{Exclude: []string{"lorem"}},
+				DeletionPolicy: v1alpha1.DeletionPolicyDelete,
+			},
+		},
+	}
+
+	objects := []client.Object{
+		namespace("default", map[string]string{"tier": "app"}),
+		namespace("testing", nil),
+		namespace("foo", map[string]string{"tier": "app"}),
+		namespace("bar", nil),
+		namespace("disallowed", map[string]string{"tier": "app"}),
+		source,
+		policy,
+	}
+	fakeClient := newFakeClient(t, objects...)
+	r := NewReconciler(fakeClient, fakeClient, newIndex(objects...),
+		&config.Config{DisallowedNamespaces: []string{"disallowed"}}, &record.FakeRecorder{})
+	req := reconcile.Request{NamespacedName: client.ObjectKeyFromObject(policy)}
+
+	t.Run("replicate to targets", func(t *testing.T) {
+		_, err := r.Reconcile(t.Context(), req) // adds finalizer
+		assert.NoError(t, err)
+		_, err = r.Reconcile(t.Context(), req)
+		assert.NoError(t, err)
+
+		for _, ns := range []string{"testing", "foo"} {
+			var replica corev1.ConfigMap
+			err := fakeClient.Get(t.Context(), client.ObjectKey{Namespace: ns, Name: source.Name}, &replica)
+			assert.NoError(t, err)
+			assert.Equal(t, map[string]string{"foo": "bar"}, replica.Data)
+			assert.Equal(t, "policy", replica.Labels[replicator.PolicyLabel])
+		}
+		for _, ns := range []string{"bar", "disallowed"} {
+			err := fakeClient.Get(t.Context(), client.ObjectKey{Namespace: ns, Name: source.Name}, &corev1.ConfigMap{})
+			assert.True(t, apierrors.IsNotFound(err))
+		}
+
+		var actual v1alpha1.ReplicationPolicy
+		err = fakeClient.Get(t.Context(), req.NamespacedName, &actual)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"foo", "testing"}, actual.Status.ReplicatedTo)
+		assert.True(t, meta.IsStatusConditionTrue(actual.Status.Conditions, v1alpha1.ConditionTypeReady))
+	})
+
+	t.Run("prune namespaces no longer targeted", func(t *testing.T) {
+		var actual v1alpha1.ReplicationPolicy
+		err := fakeClient.Get(t.Context(), req.NamespacedName, &actual)
+		assert.NoError(t, err)
+		actual.Spec.Targets.NamespaceSelector = nil
+		assert.NoError(t, fakeClient.Update(t.Context(), &actual))
+
+		_, err = r.Reconcile(t.Context(), req)
+		assert.NoError(t, err)
+
+		err = fakeClient.Get(t.Context(), client.ObjectKey{Namespace: "foo", Name: source.Name}, &corev1.ConfigMap{})
+		assert.True(t, apierrors.IsNotFound(err))
+		err = fakeClient.Get(t.Context(), client.ObjectKey{Namespace: "testing", Name: source.Name}, &corev1.ConfigMap{})
+		assert.NoError(t, err)
+	})
+
+	t.Run("retain replicas when policy is deleted", func(t *testing.T) {
+		var actual v1alpha1.ReplicationPolicy
+		err := fakeClient.Get(t.Context(), req.NamespacedName, &actual)
+		assert.NoError(t, err)
+		actual.Spec.DeletionPolicy = v1alpha1.DeletionPolicyRetain
+		assert.NoError(t, fakeClient.Update(t.Context(), &actual))
+		assert.NoError(t, fakeClient.Delete(t.Context(), &actual))
+
+		_, err = r.Reconcile(t.Context(), req)
+		assert.NoError(t, err)
+
+		var replica corev1.ConfigMap
+		err = fakeClient.Get(t.Context(), client.ObjectKey{Namespace: "testing", Name: source.Name}, &replica)
+		assert.NoError(t, err)
+		assert.NotContains(t, replica.Labels, replicator.PolicyLabel)
+		assert.NotContains(t, replica.Labels, replicator.SourceNameLabel)
+
+		err = fakeClient.Get(t.Context(), req.NamespacedName, &actual)
+		assert.True(t, apierrors.IsNotFound(err))
+	})
+}
+
+func TestReconciler_Reconcile_sourceNotFound(t *testing.T) {
+	policy := &v1alpha1.ReplicationPolicy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       "policy",
+			Namespace:  "default",
+			Finalizers: []string{policyFinalizer},
+		},
+		Spec: v1alpha1.ReplicationPolicySpec{
+			Source: v1alpha1.SourceReference{Kind: v1alpha1.SourceKindSecret, Name: "missing"},
+			ReplicationSpec: v1alpha1.ReplicationSpec{
+				Targets: v1alpha1.Targets{Namespaces: []string{"testing"}},
+			},
+		},
+	}
+	fakeClient := newFakeClient(t, namespace("testing", nil), policy)
+	r := NewReconciler(fakeClient, fakeClient, newIndex(namespace("testing", nil)), &config.Config{},
+		&record.FakeRecorder{})
+
+	_, err := r.Reconcile(t.Context(), reconcile.Request{NamespacedName: client.ObjectKeyFromObject(policy)})
+	assert.NoError(t, err)
+
+	var actual v1alpha1.ReplicationPolicy
+	err = fakeClient.Get(t.Context(), client.ObjectKeyFromObject(policy), &actual)
+	assert.NoError(t, err)
+
+	condition := meta.FindStatusCondition(actual.Status.Conditions, v1alpha1.ConditionTypeReady)
+	assert.NotNil(t, condition)
+	assert.Equal(t, metav1.ConditionFalse, condition.Status)
+	assert.Equal(t, reasonSourceNotFound, condition.Reason)
+}
+
+func TestReconciler_Reconcile_sourceNotAllowed(t *testing.T) {
+	source := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "source-name", Namespace: "kube-system"}}
+	policy := &v1alpha1.ReplicationPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "policy", Namespace: "kube-system", Finalizers: []string{policyFinalizer}},
+		Spec: v1alpha1.ReplicationPolicySpec{
+			Source: v1alpha1.SourceReference{Kind: v1alpha1.SourceKindConfigMap, Name: "source-name"},
+			ReplicationSpec: v1alpha1.ReplicationSpec{
+				Targets: v1alpha1.Targets{Namespaces: []string{"testing"}},
+			},
+		},
+	}
+	fakeClient := newFakeClient(t, namespace("testing", nil), source, policy)
+	r := NewReconciler(fakeClient, fakeClient, newIndex(namespace("testing", nil)),
+		&config.Config{DisallowedSourceNamespaces: []string{"kube-system"}}, &record.FakeRecorder{})
+
+	_, err := r.Reconcile(t.Context(), reconcile.Request{NamespacedName: client.ObjectKeyFromObject(policy)})
+	assert.NoError(t, err)
+
+	err = fakeClient.Get(t.Context(), client.ObjectKey{Namespace: "testing", Name: source.Name}, &corev1.ConfigMap{})
+	assert.True(t, apierrors.IsNotFound(err))
+
+	var actual v1alpha1.ReplicationPolicy
+	err = fakeClient.Get(t.Context(), client.ObjectKeyFromObject(policy), &actual)
+	assert.NoError(t, err)
+
+	condition := meta.FindStatusCondition(actual.Status.Conditions, v1alpha1.ConditionTypeReady)
+	assert.NotNil(t, condition)
+	assert.Equal(t, metav1.ConditionFalse, condition.Status)
+	assert.Equal(t, reasonSourceNotAllowed, condition.Reason)
+}
+
+func TestReconciler_Reconcile_authorizeTargets(t *testing.T) {
+	source := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "source-name", Namespace: "default"},
+		Data:       map[string][]byte{"foo": []byte("bar")},
+	}
+	policy := &v1alpha1.ReplicationPolicy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "policy",
+			Namespace:   "default",
+			Finalizers:  []string{policyFinalizer},
+			Annotations: map[string]string{replicator.RunAsAnnotation: "replicator"},
+		},
+		Spec: v1alpha1.ReplicationPolicySpec{
+			Source: v1alpha1.SourceReference{Kind: v1alpha1.SourceKindSecret, Name: "source-name"},
+			ReplicationSpec: v1alpha1.ReplicationSpec{
+				Targets: v1alpha1.Targets{Namespaces: []string{"testing", "kube-system"}},
+			},
+		},
+	}
+
+	objects := []client.Object{namespace("testing", nil), namespace("kube-system", nil), source, policy}
+	scheme := runtime.NewScheme()
+	assert.NoError(t, corev1.AddToScheme(scheme))
+	assert.NoError(t, v1alpha1.AddToScheme(scheme))
+	assert.NoError(t, authorizationv1.AddToScheme(scheme))
+
+	var users []string
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(objects...).
+		WithStatusSubresource(&v1alpha1.ReplicationPolicy{}).
+		WithInterceptorFuncs(interceptor.Funcs{
+			Create: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.CreateOption) error {
+				review, ok := obj.(*authorizationv1.SubjectAccessReview)
+				if !ok {
+					return c.Create(ctx, obj, opts...)
+				}
+				users = append(users, review.Spec.User)
+				review.Status.Allowed = review.Spec.ResourceAttributes.Namespace == "testing"
+				return nil
+			},
+		}).
+		Build()
+	recorder := record.NewFakeRecorder(10)
+	r := NewReconciler(fakeClient, fakeClient, newIndex(objects...), &config.Config{AuthorizeTargets: true}, recorder)
+
+	_, err := r.Reconcile(t.Context(), reconcile.Request{NamespacedName: client.ObjectKeyFromObject(policy)})
+	assert.NoError(t, err)
+
+	err = fakeClient.Get(t.Context(), client.ObjectKey{Namespace: "testing", Name: source.Name}, &corev1.Secret{})
+	assert.NoError(t, err)
+	err = fakeClient.Get(t.Context(), client.ObjectKey{Namespace: "kube-system", Name: source.Name}, &corev1.Secret{})
+	assert.True(t, apierrors.IsNotFound(err))
+
+	assert.Equal(t, "Warning Unauthorized ServiceAccount default/replicator may not write secrets "+
+		"to namespaces kube-system", <-recorder.Events)
+	for _, user := range users {
+		assert.Equal(t, "system:serviceaccount:default:replicator", user)
+	}
+}
+
+func TestReconciler_Reconcile_precedence(t *testing.T) {
+	source := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "source-name", Namespace: "default"},
+		Data:       map[string][]byte{"foo": []byte("bar")},
+	}
+	policy := &v1alpha1.ReplicationPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "policy", Namespace: "default", Finalizers: []string{policyFinalizer}},
+		Spec: v1alpha1.ReplicationPolicySpec{
+			Source: v1alpha1.SourceReference{Kind: v1alpha1.SourceKindSecret, Name: "source-name"},
+			ReplicationSpec: v1alpha1.ReplicationSpec{
+				Targets: v1alpha1.Targets{Namespaces: []string{"testing"}},
+			},
+		},
+	}
+	clusterPolicy := &v1alpha1.ClusterReplicationPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "cluster-policy", Finalizers: []string{policyFinalizer}},
+		Spec: v1alpha1.ClusterReplicationPolicySpec{
+			Source: v1alpha1.NamespacedSourceReference{
+				SourceReference: v1alpha1.SourceReference{Kind: v1alpha1.SourceKindSecret, Name: "source-name"},
+				Namespace:       "default",
+			},
+			ReplicationSpec: v1alpha1.ReplicationSpec{
+				Targets: v1alpha1.Targets{Namespaces: []string{"testing", "default"}},
+			},
+		},
+	}
+
+	objects := []client.Object{namespace("default", nil), namespace("testing", nil), source, policy, clusterPolicy}
+	fakeClient := newFakeClient(t, objects...)
+	r := NewReconciler(fakeClient, fakeClient, newIndex(objects...), &config.Config{}, &record.FakeRecorder{})
+	clusterReconciler := NewClusterReconciler(fakeClient, fakeClient, newIndex(objects...), &config.Config{},
+		&record.FakeRecorder{})
+
+	t.Run("replicate by policy", func(t *testing.T) {
+		_, err := r.Reconcile(t.Context(), reconcile.Request{NamespacedName: client.ObjectKeyFromObject(policy)})
+		assert.NoError(t, err)
+
+		var replica corev1.Secret
+		err = fakeClient.Get(t.Context(), client.ObjectKey{Namespace: "testing", Name: source.Name}, &replica)
+		assert.NoError(t, err)
+		assert.Equal(t, "policy", replica.Labels[replicator.PolicyLabel])
+	})
+
+	t.Run("cluster policy takes over replica", func(t *testing.T) {
+		_, err := clusterReconciler.Reconcile(t.Context(),
+			reconcile.Request{NamespacedName: client.ObjectKeyFromObject(clusterPolicy)})
+		assert.NoError(t, err)
+
+		var replica corev1.Secret
+		err = fakeClient.Get(t.Context(), client.ObjectKey{Namespace: "testing", Name: source.Name}, &replica)
+		assert.NoError(t, err)
+		assert.Equal(t, "cluster-policy", replica.Labels[replicator.ClusterPolicyLabel])
+		assert.NotContains(t, replica.Labels, replicator.PolicyLabel)
+
+		// the namespace of the source is never targeted
+		var actualSource corev1.Secret
+		err = fakeClient.Get(t.Context(), client.ObjectKeyFromObject(source), &actualSource)
+		assert.NoError(t, err)
+		assert.NotContains(t, actualSource.Labels, replicator.ClusterPolicyLabel)
+
+		var actual v1alpha1.ClusterReplicationPolicy
+		err = fakeClient.Get(t.Context(), client.ObjectKeyFromObject(clusterPolicy), &actual)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"testing"}, actual.Status.ReplicatedTo)
+	})
+
+	t.Run("policy is overridden", func(t *testing.T) {
+		_, err := r.Reconcile(t.Context(), reconcile.Request{NamespacedName: client.ObjectKeyFromObject(policy)})
+		assert.NoError(t, err)
+
+		var replica corev1.Secret
+		err = fakeClient.Get(t.Context(), client.ObjectKey{Namespace: "testing", Name: source.Name}, &replica)
+		assert.NoError(t, err)
+		assert.Equal(t, "cluster-policy", replica.Labels[replicator.ClusterPolicyLabel])
+
+		var actual v1alpha1.ReplicationPolicy
+		err = fakeClient.Get(t.Context(), client.ObjectKeyFromObject(policy), &actual)
+		assert.NoError(t, err)
+		assert.Empty(t, actual.Status.ReplicatedTo)
+		assert.True(t, meta.IsStatusConditionTrue(actual.Status.Conditions, v1alpha1.ConditionTypeReady))
+	})
+}
@@ -0,0 +1,267 @@
+package report
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/c0deltin/replik8or/internal/config"
+	"github.com/c0deltin/replik8or/internal/controller/orphan"
+	"github.com/c0deltin/replik8or/internal/controller/staleness"
+	"github.com/c0deltin/replik8or/internal/replicator"
+)
+
+// reportKey is the key of the report within the data of the report ConfigMap.
+const reportKey = "report.json"
+
+// namespaceFile contains the namespace of the pod the operator is running in.
+const namespaceFile = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+
+// Report summarizes the sources and replicas of a kind.
+type Report struct {
+	Kind    string `json:"kind"`
+	Sources int    `json:"sources"`
+	// Replicas are the number of replicas by the namespaced name of their source.
+	Replicas  map[string]int `json:"replicas"`
+	Orphans   int            `json:"orphans"`
+	Conflicts int            `json:"conflicts"`
+	Stale     int            `json:"stale"`
+}
+
+// Summary is the startup report of all kinds.
+type Summary struct {
+	GeneratedAt time.Time `json:"generatedAt"`
+	Kinds       []Report  `json:"kinds"`
+}
+
+// Reporter creates the Report of a single kind.
+type Reporter interface {
+	Report(ctx context.Context) (Report, error)
+}
+
+// Runnable reports the state of all kinds once the cache of the manager is synced. The report is logged and, if
+// enabled by configuration, written into a ConfigMap.
+type Runnable struct {
+	client client.Client
+	// apiReader reads the report ConfigMap, whose namespace is not cached if the operator is restricted to others
+	apiReader client.Reader
+	cache     cache.Cache
+	config    *config.Config
+
+	reporters []Reporter
+}
+
+func NewRunnable(
+	client client.Client,
+	apiReader client.Reader,
+	cache cache.Cache,
+	config *config.Config,
+	reporters ...Reporter,
+) *Runnable {
+	return &Runnable{
+		client:    client,
+		apiReader: apiReader,
+		cache:     cache,
+		config:    config,
+		reporters: reporters,
+	}
+}
+
+// Start reports once after the cache is synced. A failed report never stops the manager.
+func (r *Runnable) Start(ctx context.Context) error {
+	lgr := log.FromContext(ctx).WithName("report")
+
+	if !r.cache.WaitForCacheSync(ctx) {
+		return nil
+	}
+
+	summary, err := r.summarize(ctx)
+	if err != nil {
+		lgr.Error(err, "creating startup report")
+		return nil
+	}
+	for _, report := range summary.Kinds {
+		lgr.Info("startup report",
+			"kind", report.Kind,
+			"sources", report.Sources,
+			"replicas", total(report.Replicas),
+			"orphans", report.Orphans,
+			"conflicts", report.Conflicts,
+			"stale", report.Stale,
+		)
+	}
+
+	if r.config.ReportConfigMap == "" {
+		return nil
+	}
+	if err := r.write(ctx, summary); err != nil {
+		lgr.Error(err, "writing startup report", "configMap", r.config.ReportConfigMap)
+	}
+	return nil
+}
+
+// NeedLeaderElection makes sure only the leader reports, as only the leader reconciles.
+func (r *Runnable) NeedLeaderElection() bool {
+	return true
+}
+
+// summarize creates the reports of all kinds.
+func (r *Runnable) summarize(ctx context.Context) (Summary, error) {
+	summary := Summary{GeneratedAt: time.Now().UTC()}
+	for _, reporter := range r.reporters {
+		report, err := reporter.Report(ctx)
+		if err != nil {
+			return Summary{}, err
+		}
+		summary.Kinds = append(summary.Kinds, report)
+	}
+	return summary, nil
+}
+
+// write stores summary in the report ConfigMap, which is located in the namespace of the operator by default.
+func (r *Runnable) write(ctx context.Context, summary Summary) error {
+	namespace := r.config.ReportNamespace
+	if namespace == "" {
+		data, err := os.ReadFile(namespaceFile)
+		if err != nil {
+			return fmt.Errorf("reading namespace of the operator: %w", err)
+		}
+		namespace = strings.TrimSpace(string(data))
+	}
+
+	data, err := json.Marshal(summary)
+	if err != nil {
+		return err
+	}
+
+	// the ConfigMap is read from the API server, as its namespace might not be watched
+	configMap := &corev1.ConfigMap{}
+	err = r.apiReader.Get(ctx, client.ObjectKey{Namespace: namespace, Name: r.config.ReportConfigMap}, configMap)
+	if client.IgnoreNotFound(err) != nil {
+		return err
+	}
+	exists := err == nil
+
+	configMap.Name = r.config.ReportConfigMap
+	configMap.Namespace = namespace
+	configMap.Data = map[string]string{reportKey: string(data)}
+	if exists {
+		return r.client.Update(ctx, configMap)
+	}
+	return r.client.Create(ctx, configMap)
+}
+
+// Kind creates the Report of the sources and replicas of type T.
+type Kind[T client.Object] struct {
+	client client.Client
+
+	emptyObjectFn     func() T
+	emptyObjectListFn func() client.ObjectList
+
+	orphans *orphan.Collector[T]
+	stale   *staleness.Checker[T]
+}
+
+func NewKind[T client.Object](
+	client client.Client,
+	emptyObjectFn func() T,
+	emptyObjectListFn func() client.ObjectList,
+	orphans *orphan.Collector[T],
+	stale *staleness.Checker[T],
+) *Kind[T] {
+	return &Kind[T]{
+		client:            client,
+		emptyObjectFn:     emptyObjectFn,
+		emptyObjectListFn: emptyObjectListFn,
+		orphans:           orphans,
+		stale:             stale,
+	}
+}
+
+// Report lists all objects of the kind and counts sources, replicas, conflicts and stale replicas. Conflicts are
+// taken from the replication status of the sources, stale replicas are counted like by the staleness check.
+func (k *Kind[T]) Report(ctx context.Context) (Report, error) {
+	var objectList = k.emptyObjectListFn()
+	if err := k.client.List(ctx, objectList); err != nil {
+		return Report{}, err
+	}
+
+	objects, err := meta.ExtractList(objectList)
+	if err != nil {
+		return Report{}, err
+	}
+
+	report := Report{
+		Kind:     replicator.Kind(k.emptyObjectFn()),
+		Replicas: map[string]int{},
+	}
+	// sources are read like by the staleness check, as the data of policy sources is not cached
+	sources := staleness.NewSources(k.stale)
+	for _, o := range objects {
+		object := o.(client.Object)
+		if replicator.HasAnnotations(object, replicator.ReplicationAllowedAnnotation) {
+			report.Sources++
+			report.Conflicts += conflicts(object)
+		}
+
+		if !replicator.HasLabels(object, replicator.SourceNamespaceLabel, replicator.SourceNameLabel) {
+			continue
+		}
+		key := client.ObjectKey{
+			Namespace: object.GetLabels()[replicator.SourceNamespaceLabel],
+			Name:      object.GetLabels()[replicator.SourceNameLabel],
+		}
+		report.Replicas[key.String()]++
+
+		source, ok, err := sources.Get(ctx, object)
+		if err != nil {
+			log.FromContext(ctx).Error(err, "skipping staleness of replicas of unreadable source", "source", key)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		isStale, err := k.stale.IsStale(ctx, object, source)
+		if err != nil {
+			return Report{}, err
+		}
+		if isStale {
+			report.Stale++
+		}
+	}
+
+	orphans, err := k.orphans.Find(ctx)
+	if err != nil {
+		return Report{}, fmt.Errorf("finding orphaned %ss: %w", report.Kind, err)
+	}
+	report.Orphans = len(orphans)
+
+	return report, nil
+}
+
+// conflicts returns the number of namespaces in which the replication of source failed with a conflict.
+func conflicts(source client.Object) int {
+	namespaces := source.GetAnnotations()[replicator.ConflictsAnnotation]
+	if namespaces == "" {
+		return 0
+	}
+	return len(strings.Split(namespaces, ","))
+}
+
+// total returns the sum of all replicas.
+func total(replicas map[string]int) int {
+	var sum int
+	for _, count := range replicas {
+		sum += count
+	}
+	return sum
+}
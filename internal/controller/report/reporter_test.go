@@ -0,0 +1,138 @@
+package report
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/c0deltin/replik8or/internal/config"
+	"github.com/c0deltin/replik8or/internal/controller/orphan"
+	"github.com/c0deltin/replik8or/internal/controller/staleness"
+	"github.com/c0deltin/replik8or/internal/replicator"
+)
+
+func TestRunnable_report(t *testing.T) {
+	source := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              "source-name",
+			Namespace:         "source-namespace",
+			CreationTimestamp: metav1.NewTime(time.Now().Add(-time.Hour)),
+			Annotations: map[string]string{
+				replicator.ReplicationAllowedAnnotation: "true",
+				replicator.DesiredNamespacesAnnotation:  "up-to-date,outdated",
+				replicator.ConflictsAnnotation:          "conflict",
+			},
+		},
+	}
+	hash, err := replicator.ContentHash(source)
+	require.NoError(t, err)
+
+	upToDate := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "source-name",
+			Namespace: "up-to-date",
+			Labels: map[string]string{
+				replicator.SourceNamespaceLabel: "source-namespace",
+				replicator.SourceNameLabel:      "source-name",
+			},
+			Annotations: map[string]string{replicator.ContentHashAnnotation: hash},
+		},
+	}
+	outdated := upToDate.DeepCopy()
+	outdated.Namespace = "outdated"
+	outdated.Annotations[replicator.ContentHashAnnotation] = "outdated"
+	// replicas no longer targeted by their source are orphaned and not stale
+	notTargeted := outdated.DeepCopy()
+	notTargeted.Namespace = "not-targeted"
+	orphaned := outdated.DeepCopy()
+	orphaned.Name = "deleted-source"
+	orphaned.Labels[replicator.SourceNameLabel] = "deleted-source"
+
+	// the source of a policy is not annotated, so its data is stripped from the cache
+	policySource := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              "policy-source",
+			Namespace:         "source-namespace",
+			CreationTimestamp: metav1.NewTime(time.Now().Add(-time.Hour)),
+		},
+		Data: map[string]string{"foo": "bar"},
+	}
+	policyHash, err := replicator.ContentHash(policySource)
+	require.NoError(t, err)
+	strippedPolicySource := policySource.DeepCopy()
+	strippedPolicySource.Data = nil
+	policyReplica := upToDate.DeepCopy()
+	policyReplica.Name = "policy-source"
+	policyReplica.Labels[replicator.SourceNameLabel] = "policy-source"
+	policyReplica.Labels[replicator.PolicyLabel] = "policy"
+	policyReplica.Annotations[replicator.ContentHashAnnotation] = policyHash
+
+	cfg := &config.Config{
+		StalenessThreshold: time.Minute,
+		ReportConfigMap:    "report",
+		ReportNamespace:    "replik8or",
+	}
+	fakeClient := fake.NewClientBuilder().
+		WithObjects(source, upToDate, outdated, notTargeted, orphaned, strippedPolicySource, policyReplica).
+		Build()
+	apiReader := fake.NewClientBuilder().WithObjects(policySource).Build()
+
+	orphans := orphan.NewCollector[*corev1.ConfigMap](
+		fakeClient,
+		cfg,
+		&record.FakeRecorder{},
+		replicator.EmptyConfigMap,
+		replicator.EmptyConfigMapList,
+	)
+	r := NewRunnable(fakeClient, fakeClient, nil, cfg, NewKind[*corev1.ConfigMap](
+		fakeClient,
+		replicator.EmptyConfigMap,
+		replicator.EmptyConfigMapList,
+		orphans,
+		staleness.NewChecker[*corev1.ConfigMap](
+			fakeClient,
+			apiReader,
+			cfg,
+			&record.FakeRecorder{},
+			replicator.EmptyConfigMap,
+			replicator.EmptyConfigMapList,
+			orphans,
+			staleness.Requeue{},
+		),
+	))
+
+	summary, err := r.summarize(t.Context())
+	require.NoError(t, err)
+	require.Len(t, summary.Kinds, 1)
+	assert.Equal(t, Report{
+		Kind:    "ConfigMap",
+		Sources: 1,
+		Replicas: map[string]int{
+			"source-namespace/source-name":    3,
+			"source-namespace/deleted-source": 1,
+			"source-namespace/policy-source":  1,
+		},
+		Orphans:   2,
+		Conflicts: 1,
+		Stale:     1,
+	}, summary.Kinds[0])
+
+	require.NoError(t, r.write(t.Context(), summary))
+	// an existing report is updated
+	require.NoError(t, r.write(t.Context(), summary))
+
+	var configMap corev1.ConfigMap
+	require.NoError(t, fakeClient.Get(t.Context(), client.ObjectKey{Namespace: "replik8or", Name: "report"}, &configMap))
+
+	var written Summary
+	require.NoError(t, json.Unmarshal([]byte(configMap.Data[reportKey]), &written))
+	assert.Equal(t, summary.Kinds, written.Kinds)
+}
@@ -2,10 +2,13 @@ package source
 
 import (
 	"context"
+	"maps"
 
 	"github.com/go-logr/logr"
-	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/workqueue"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -14,7 +17,10 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	ctrlsource "sigs.k8s.io/controller-runtime/pkg/source"
 
+	"github.com/c0deltin/replik8or/internal/controller"
+	"github.com/c0deltin/replik8or/internal/namespaces"
 	"github.com/c0deltin/replik8or/internal/replicator"
 )
 
@@ -28,23 +34,55 @@ func (r *Reconciler[T]) SetupWithManager(name string, mgr manager.Manager) error
 		return err
 	}
 
-	return builder.ControllerManagedBy(mgr).
+	if err := builder.ControllerManagedBy(mgr).
 		Named(name).
 		For(r.emptyObjectFn(), builder.WithPredicates(r.sourcePredicates())).
+		// replicas share the informer of the sources, their data is removed by replicator.StripUnreadData
 		Watches(
 			r.emptyObjectFn(),
 			handler.EnqueueRequestsFromMapFunc(r.enqueueReplicas),
 			builder.WithPredicates(r.replicaPredicates()),
 		).
-		Watches(
-			&corev1.Namespace{},
-			handler.EnqueueRequestsFromMapFunc(r.mapNamespacesToSources),
-			builder.WithPredicates(r.namespacePredicates()),
-		).
+		WatchesRawSource(ctrlsource.Channel(r.requeue, &handler.EnqueueRequestForObject{})).
+		WithOptions(controller.Options(r.config)).
 		WithLogConstructor(func(r *reconcile.Request) logr.Logger {
 			return ctrl.Log.WithName("replik8or")
 		}).
-		Complete(r)
+		Complete(r); err != nil {
+		return err
+	}
+
+	// namespace events and failed namespaces are queued per source and namespace, so only the replica in the
+	// namespace is written
+	namespaceController := builder.TypedControllerManagedBy[namespaceRequest](mgr).
+		Named(name + "-namespace").
+		WatchesRawSource(ctrlsource.TypedChannel(r.retries, handler.TypedFuncs[namespaceRequest, namespaceRequest]{
+			GenericFunc: func(
+				_ context.Context,
+				e event.TypedGenericEvent[namespaceRequest],
+				q workqueue.TypedRateLimitingInterface[namespaceRequest],
+			) {
+				// the namespace just failed, so its retry is delayed like the ones of failed reconciliations
+				q.AddRateLimited(e.Object)
+			},
+		}))
+
+	// namespaces are not watched when the operator is restricted to a fixed set of them
+	if len(r.config.WatchNamespaces) == 0 {
+		namespaceController = namespaceController.WatchesRawSource(ctrlsource.TypedKind(
+			mgr.GetCache(),
+			namespaces.Metadata(),
+			handler.TypedEnqueueRequestsFromMapFunc(r.mapNamespaceToRequests),
+			r.namespacePredicates(),
+		))
+	}
+
+	return namespaceController.
+		WithOptions(controller.TypedOptions[namespaceRequest](r.config)).
+		WithLogConstructor(func(r *namespaceRequest) logr.Logger {
+			return ctrl.Log.WithName("replik8or")
+		}).
+		Complete(reconcile.TypedFunc[namespaceRequest](r.reconcileNamespace))
 }
 
 func (r *Reconciler[T]) setReflectionIndexer(mgr manager.Manager) error {
@@ -61,26 +99,20 @@ func (r *Reconciler[T]) setReflectionIndexer(mgr manager.Manager) error {
 	)
 }
 
-func (r *Reconciler[T]) namespacePredicates() predicate.Predicate {
-	return predicate.Funcs{
-		CreateFunc: func(e event.CreateEvent) bool {
-			namespace, ok := e.Object.(*corev1.Namespace)
-			if !ok {
-				return false
-			}
-			return namespace.Status.Phase == corev1.NamespaceActive
+// namespacePredicates filters the events of active and deleted namespaces. Namespaces are watched by their metadata
+// only, a namespace is terminating as soon as its deletion timestamp is set.
+func (r *Reconciler[T]) namespacePredicates() predicate.TypedPredicate[*metav1.PartialObjectMetadata] {
+	return predicate.TypedFuncs[*metav1.PartialObjectMetadata]{
+		CreateFunc: func(e event.TypedCreateEvent[*metav1.PartialObjectMetadata]) bool {
+			return e.Object.GetDeletionTimestamp().IsZero()
 		},
-		UpdateFunc: func(e event.UpdateEvent) bool {
-			namespace, ok := e.ObjectNew.(*corev1.Namespace)
-			if !ok {
-				return false
-			}
-			return namespace.Status.Phase == corev1.NamespaceActive
+		UpdateFunc: func(e event.TypedUpdateEvent[*metav1.PartialObjectMetadata]) bool {
+			return e.ObjectNew.GetDeletionTimestamp().IsZero()
 		},
-		DeleteFunc: func(e event.DeleteEvent) bool {
-			return false
+		DeleteFunc: func(e event.TypedDeleteEvent[*metav1.PartialObjectMetadata]) bool {
+			return true
 		},
-		GenericFunc: func(e event.GenericEvent) bool {
+		GenericFunc: func(e event.TypedGenericEvent[*metav1.PartialObjectMetadata]) bool {
 			return false
 		},
 	}
@@ -113,10 +145,11 @@ func (r *Reconciler[T]) replicaPredicates() predicate.Predicate {
 			return false
 		},
 		UpdateFunc: func(e event.UpdateEvent) bool {
-			return replicator.HasLabels(e.ObjectOld, replicator.SourceNamespaceLabel, replicator.SourceNameLabel)
+			return isAnnotationReplica(e.ObjectOld)
 		},
 		DeleteFunc: func(e event.DeleteEvent) bool {
-			return replicator.HasLabels(e.Object, replicator.SourceNamespaceLabel, replicator.SourceNameLabel)
+			// a deleted policy replica frees the namespace for the replication by annotation
+			return isReplica(e.Object)
 		},
 		GenericFunc: func(e event.GenericEvent) bool {
 			return false
@@ -124,6 +157,17 @@ func (r *Reconciler[T]) replicaPredicates() predicate.Predicate {
 	}
 }
 
+// isReplica reports whether object is labeled as replica of any source.
+func isReplica(object client.Object) bool {
+	return replicator.HasLabels(object, replicator.SourceNamespaceLabel, replicator.SourceNameLabel)
+}
+
+// isAnnotationReplica reports whether object is a replica of a source replicated by annotation. Replicas managed by
+// a policy are left to the policy controllers.
+func isAnnotationReplica(object client.Object) bool {
+	return isReplica(object) && !replicator.IsPolicyReplica(object)
+}
+
 func (r *Reconciler[T]) enqueueReplicas(_ context.Context, object client.Object) []reconcile.Request {
 	var result []reconcile.Request
 	if replicator.HasLabels(object, replicator.SourceNamespaceLabel, replicator.SourceNameLabel) {
@@ -145,7 +189,8 @@ func (r *Reconciler[T]) sourcePredicates() predicate.Funcs {
 			return replicator.HasAnnotations(e.Object, replicator.ReplicationAllowedAnnotation)
 		},
 		UpdateFunc: func(e event.UpdateEvent) bool {
-			return replicator.HasAnnotations(e.ObjectOld, replicator.ReplicationAllowedAnnotation)
+			return replicator.HasAnnotations(e.ObjectOld, replicator.ReplicationAllowedAnnotation) &&
+				!isStatusUpdate(e.ObjectOld, e.ObjectNew)
 		},
 		DeleteFunc: func(e event.DeleteEvent) bool {
 			return replicator.HasAnnotations(e.Object, replicator.ReplicationAllowedAnnotation)
@@ -155,3 +200,24 @@ func (r *Reconciler[T]) sourcePredicates() predicate.Funcs {
 		},
 	}
 }
+
+// isStatusUpdate reports whether the source was only changed by writing its status annotations, which does not
+// require another reconciliation. Resyncs of the cache do not change the resource version and are always passed.
+func isStatusUpdate(oldSource, newSource client.Object) bool {
+	if oldSource.GetResourceVersion() == newSource.GetResourceVersion() {
+		return false
+	}
+
+	withoutStatus := func(source client.Object) client.Object {
+		source = source.DeepCopyObject().(client.Object)
+		source.SetResourceVersion("")
+		source.SetManagedFields(nil)
+		annotations := maps.Clone(source.GetAnnotations())
+		for _, key := range statusAnnotations {
+			delete(annotations, key)
+		}
+		source.SetAnnotations(annotations)
+		return source
+	}
+	return equality.Semantic.DeepEqual(withoutStatus(oldSource), withoutStatus(newSource))
+}
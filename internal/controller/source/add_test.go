@@ -2,6 +2,7 @@ package source
 
 import (
 	"context"
+	"maps"
 	"testing"
 
 	"github.com/c0deltin/replik8or/internal/replicator"
@@ -9,6 +10,7 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 )
 
@@ -39,3 +41,81 @@ func TestReconciler_enqueueReplicas(t *testing.T) {
 
 	assert.Equal(t, expected, actual)
 }
+
+func TestIsAnnotationReplica(t *testing.T) {
+	replicaLabels := map[string]string{
+		replicator.SourceNamespaceLabel: "source-namespace",
+		replicator.SourceNameLabel:      "source-name",
+	}
+	withLabel := func(key, value string) map[string]string {
+		labels := maps.Clone(replicaLabels)
+		labels[key] = value
+		return labels
+	}
+
+	tests := []struct {
+		name     string
+		labels   map[string]string
+		expected bool
+	}{
+		{name: "annotation replica", labels: replicaLabels, expected: true},
+		{name: "policy replica", labels: withLabel(replicator.PolicyLabel, "policy")},
+		{name: "cluster policy replica", labels: withLabel(replicator.ClusterPolicyLabel, "cluster-policy")},
+		{name: "no replica"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			object := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Labels: tt.labels}}
+			assert.Equal(t, tt.expected, isAnnotationReplica(object))
+		})
+	}
+}
+
+func TestReconciler_namespacePredicates(t *testing.T) {
+	r := Reconciler[*corev1.ConfigMap]{}
+
+	now := metav1.Now()
+	active := &metav1.PartialObjectMetadata{ObjectMeta: metav1.ObjectMeta{Name: "active"}}
+	terminating := &metav1.PartialObjectMetadata{ObjectMeta: metav1.ObjectMeta{
+		Name:              "terminating",
+		DeletionTimestamp: &now,
+	}}
+
+	predicates := r.namespacePredicates()
+	assert.True(t, predicates.Create(event.TypedCreateEvent[*metav1.PartialObjectMetadata]{Object: active}))
+	assert.False(t, predicates.Create(event.TypedCreateEvent[*metav1.PartialObjectMetadata]{Object: terminating}))
+	assert.True(t, predicates.Update(event.TypedUpdateEvent[*metav1.PartialObjectMetadata]{
+		ObjectOld: active,
+		ObjectNew: active,
+	}))
+	assert.False(t, predicates.Update(event.TypedUpdateEvent[*metav1.PartialObjectMetadata]{
+		ObjectOld: active,
+		ObjectNew: terminating,
+	}))
+	assert.True(t, predicates.Delete(event.TypedDeleteEvent[*metav1.PartialObjectMetadata]{Object: terminating}))
+}
+
+func TestIsStatusUpdate(t *testing.T) {
+	source := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "source-name",
+			Namespace:       "source-namespace",
+			ResourceVersion: "1",
+			Annotations:     map[string]string{replicator.ReplicationAllowedAnnotation: "true"},
+		},
+		Data: map[string]string{"foo": "bar"},
+	}
+
+	status := source.DeepCopy()
+	status.ResourceVersion = "2"
+	status.Annotations[replicator.ReplicatedToAnnotation] = "foo"
+	status.Annotations[replicator.LastReplicationAnnotation] = "2006-01-02T15:04:05Z"
+
+	data := status.DeepCopy()
+	data.ResourceVersion = "3"
+	data.Data["foo"] = "baz"
+
+	assert.False(t, isStatusUpdate(source, source), "resync")
+	assert.True(t, isStatusUpdate(source, status))
+	assert.False(t, isStatusUpdate(status, data))
+}
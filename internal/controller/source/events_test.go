@@ -0,0 +1,68 @@
+package source
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/c0deltin/replik8or/internal/config"
+	"github.com/c0deltin/replik8or/internal/namespaces"
+	"github.com/c0deltin/replik8or/internal/replicator"
+)
+
+func TestReconciler_Reconcile_replicatedEvent(t *testing.T) {
+	source := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       "source-name",
+			Namespace:  "source-namespace",
+			Finalizers: []string{sourceFinalizer},
+			Annotations: map[string]string{
+				replicator.ReplicationAllowedAnnotation: "true",
+			},
+		},
+	}
+
+	fakeClient := fake.NewClientBuilder().WithObjects(source).Build()
+	recorder := record.NewFakeRecorder(10)
+	r := NewReconciler[*corev1.ConfigMap](
+		fakeClient,
+		fakeClient,
+		namespaces.NewIndex(namespaces.Namespace{Name: "foo"}),
+		&config.Config{FanoutConcurrency: 1},
+		recorder,
+		replicator.EmptyConfigMap,
+		replicator.EmptyConfigMapList,
+	)
+	req := reconcile.Request{NamespacedName: client.ObjectKeyFromObject(source)}
+
+	_, err := r.Reconcile(t.Context(), req)
+	require.NoError(t, err)
+	assert.Contains(t, events(recorder), "Normal Replicated Replicated to 1 namespaces")
+
+	// nothing changed, so no event is recorded
+	_, err = r.Reconcile(t.Context(), req)
+	require.NoError(t, err)
+	assert.NotContains(t, events(recorder), "Normal Replicated Replicated to 1 namespaces")
+
+	// the status is not written in dry-run mode, so the event would be recorded on every reconciliation
+	r.config.DryRun = true
+	_, err = r.Reconcile(t.Context(), req)
+	require.NoError(t, err)
+	assert.NotContains(t, events(recorder), "Normal Replicated Replicated to 1 namespaces")
+}
+
+// events drains the events recorded so far.
+func events(recorder *record.FakeRecorder) []string {
+	var events []string
+	for len(recorder.Events) > 0 {
+		events = append(events, <-recorder.Events)
+	}
+	return events
+}
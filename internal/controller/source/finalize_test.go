@@ -0,0 +1,62 @@
+package source
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/c0deltin/replik8or/internal/config"
+	"github.com/c0deltin/replik8or/internal/namespaces"
+	"github.com/c0deltin/replik8or/internal/replicator"
+)
+
+func TestReconciler_finalizeAndDelete_dryRun(t *testing.T) {
+	source := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       "source-name",
+			Namespace:  "source-namespace",
+			Finalizers: []string{sourceFinalizer},
+			Annotations: map[string]string{
+				replicator.ReplicatedToAnnotation: "foo",
+			},
+		},
+	}
+	replica := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "source-name",
+			Namespace: "foo",
+			Labels: map[string]string{
+				replicator.SourceNamespaceLabel: "source-namespace",
+				replicator.SourceNameLabel:      "source-name",
+			},
+		},
+	}
+
+	fakeClient := fake.NewClientBuilder().WithObjects(source, replica).Build()
+	r := NewReconciler[*corev1.ConfigMap](
+		fakeClient,
+		fakeClient,
+		namespaces.NewIndex(),
+		&config.Config{DryRun: true},
+		&record.FakeRecorder{},
+		replicator.EmptyConfigMap,
+		replicator.EmptyConfigMapList,
+	)
+
+	_, err := r.finalizeAndDelete(t.Context(), source.DeepCopy())
+	require.NoError(t, err)
+
+	// neither the replica nor the source is written
+	assert.NoError(t, fakeClient.Get(t.Context(), client.ObjectKeyFromObject(replica), &corev1.ConfigMap{}))
+
+	var current corev1.ConfigMap
+	require.NoError(t, fakeClient.Get(t.Context(), client.ObjectKeyFromObject(source), &current))
+	assert.Contains(t, current.Finalizers, sourceFinalizer)
+	assert.Equal(t, "foo", current.Annotations[replicator.ReplicatedToAnnotation])
+}
@@ -0,0 +1,60 @@
+package source
+
+import (
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+var (
+	managedSources = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "replik8or_managed_sources",
+		Help: "Number of sources managed by replik8or.",
+	}, []string{"kind"})
+
+	sourceReplicas = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "replik8or_source_replicas",
+		Help: "Number of namespaces a source was replicated to.",
+	}, []string{"kind", "namespace", "name"})
+)
+
+func init() {
+	metrics.Registry.MustRegister(managedSources, sourceReplicas)
+}
+
+// sourceMetrics keeps track of the sources managed by a reconciler of a single kind.
+type sourceMetrics struct {
+	kind string
+
+	mu      sync.Mutex
+	sources map[client.ObjectKey]struct{}
+}
+
+func newSourceMetrics(kind string) *sourceMetrics {
+	return &sourceMetrics{
+		kind:    kind,
+		sources: map[client.ObjectKey]struct{}{},
+	}
+}
+
+// observe records the number of replicas of a managed source.
+func (m *sourceMetrics) observe(key client.ObjectKey, replicas int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.sources[key] = struct{}{}
+	managedSources.WithLabelValues(m.kind).Set(float64(len(m.sources)))
+	sourceReplicas.WithLabelValues(m.kind, key.Namespace, key.Name).Set(float64(replicas))
+}
+
+// forget removes a source which is no longer managed.
+func (m *sourceMetrics) forget(key client.ObjectKey) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.sources, key)
+	managedSources.WithLabelValues(m.kind).Set(float64(len(m.sources)))
+	sourceReplicas.DeleteLabelValues(m.kind, key.Namespace, key.Name)
+}
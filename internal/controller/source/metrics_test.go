@@ -0,0 +1,27 @@
+package source
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestSourceMetrics(t *testing.T) {
+	m := newSourceMetrics("Testing")
+	foo := client.ObjectKey{Namespace: "default", Name: "foo"}
+	bar := client.ObjectKey{Namespace: "default", Name: "bar"}
+
+	m.observe(foo, 3)
+	m.observe(bar, 1)
+	m.observe(foo, 2)
+
+	assert.InDelta(t, 2, testutil.ToFloat64(managedSources.WithLabelValues("Testing")), 0)
+	assert.InDelta(t, 2, testutil.ToFloat64(sourceReplicas.WithLabelValues("Testing", "default", "foo")), 0)
+
+	m.forget(foo)
+
+	assert.InDelta(t, 1, testutil.ToFloat64(managedSources.WithLabelValues("Testing")), 0)
+	assert.Equal(t, 1, testutil.CollectAndCount(sourceReplicas))
+}
@@ -0,0 +1,91 @@
+package source
+
+import (
+	"context"
+	"errors"
+
+	"go.opentelemetry.io/otel/trace"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/c0deltin/replik8or/internal/namespaces"
+	"github.com/c0deltin/replik8or/internal/tracing"
+)
+
+// namespaceRequest requests the replication of a source into a single namespace.
+type namespaceRequest struct {
+	Source    client.ObjectKey
+	Namespace string
+}
+
+// reconcileNamespace replicates the source of req into its namespace. Namespaces created or changed after a source
+// was replicated and namespaces which failed are handled this way, without writing the replicas in all other
+// namespaces again.
+func (r *Reconciler[T]) reconcileNamespace(ctx context.Context, req namespaceRequest) (result reconcile.Result, err error) {
+	ctx, span := tracer.Start(ctx, "Reconciler.reconcileNamespace", trace.WithAttributes(
+		tracing.SourceKey.String(req.Source.String()),
+		tracing.KindKey.String(r.kind),
+		tracing.TargetNamespaceKey.String(req.Namespace),
+	))
+	defer func() { tracing.End(span, err) }()
+
+	var source = r.emptyObjectFn()
+	if err := r.client.Get(ctx, req.Source, source); err != nil {
+		return reconcile.Result{}, client.IgnoreNotFound(err)
+	}
+
+	// sources which are not replicated yet or anymore are left to Reconcile
+	if !r.isReplicated(source) || (!r.config.DryRun && !controllerutil.ContainsFinalizer(source, sourceFinalizer)) {
+		return reconcile.Result{}, nil
+	}
+
+	namespace, ok, err := r.getNamespace(ctx, req.Namespace)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+
+	// replicas are removed together with their namespace, so the namespace is only removed from the status
+	status := newReplicationStatus()
+	if !ok || namespace.Phase != corev1.NamespaceActive {
+		return reconcile.Result{}, r.updateNamespaceStatus(ctx, source, req.Namespace, status)
+	}
+
+	ok, err = r.replicator.IsTargetNamespace(ctx, source, req.Namespace)
+	if err != nil || !ok {
+		return reconcile.Result{}, err
+	}
+
+	r.replicate(ctx, source, req.Namespace, status)
+	return reconcile.Result{}, errors.Join(status.err(), r.updateNamespaceStatus(ctx, source, req.Namespace, status))
+}
+
+// getNamespace returns the namespace with the given name and reports whether it exists. The index and the namespace
+// controller handle the events of the same informer independently, so a namespace missing in the index is read from
+// the cache, as it may have been created just now.
+func (r *Reconciler[T]) getNamespace(ctx context.Context, name string) (namespaces.Namespace, bool, error) {
+	namespace, ok, err := r.namespaces.Get(name)
+	if err != nil || ok {
+		return namespace, ok, err
+	}
+
+	object := namespaces.Metadata()
+	if err := r.client.Get(ctx, client.ObjectKey{Name: name}, object); err != nil {
+		return namespaces.Namespace{}, false, client.IgnoreNotFound(err)
+	}
+	return namespaces.FromObject(object), true, nil
+}
+
+// mapNamespaceToRequests requests the replication of all replicated sources into the namespace.
+func (r *Reconciler[T]) mapNamespaceToRequests(
+	ctx context.Context,
+	namespace *metav1.PartialObjectMetadata,
+) []namespaceRequest {
+	var requests []namespaceRequest
+	for _, source := range r.mapNamespacesToSources(ctx, namespace) {
+		requests = append(requests, namespaceRequest{Source: source.NamespacedName, Namespace: namespace.GetName()})
+	}
+	return requests
+}
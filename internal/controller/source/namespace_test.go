@@ -0,0 +1,270 @@
+package source
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/c0deltin/replik8or/internal/config"
+	"github.com/c0deltin/replik8or/internal/namespaces"
+	"github.com/c0deltin/replik8or/internal/replicator"
+)
+
+func TestReconciler_reconcileNamespace(t *testing.T) {
+	source := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       "source-name",
+			Namespace:  "source-namespace",
+			Finalizers: []string{sourceFinalizer},
+			Annotations: map[string]string{
+				replicator.ReplicationAllowedAnnotation: "true",
+				replicator.ReplicatedToAnnotation:       "foo",
+			},
+		},
+		Data: map[string]string{"foo": "bar"},
+	}
+
+	newReconciler := func(objects ...client.Object) (*Reconciler[*corev1.ConfigMap], client.Client) {
+		fakeClient := fake.NewClientBuilder().WithObjects(objects...).Build()
+		r := NewReconciler[*corev1.ConfigMap](
+			fakeClient,
+			fakeClient,
+			namespaces.NewIndex(namespaces.Namespace{Name: "foo"}, namespaces.Namespace{Name: "bar"}),
+			&config.Config{},
+			&record.FakeRecorder{},
+			replicator.EmptyConfigMap,
+			replicator.EmptyConfigMapList,
+		)
+		return r, fakeClient
+	}
+
+	t.Run("new namespace", func(t *testing.T) {
+		r, fakeClient := newReconciler(source.DeepCopy())
+
+		_, err := r.reconcileNamespace(t.Context(), namespaceRequest{
+			Source:    client.ObjectKeyFromObject(source),
+			Namespace: "bar",
+		})
+		require.NoError(t, err)
+
+		var replica corev1.ConfigMap
+		err = fakeClient.Get(t.Context(), client.ObjectKey{Namespace: "bar", Name: source.Name}, &replica)
+		require.NoError(t, err)
+		assert.Equal(t, source.Data, replica.Data)
+
+		// only the replica in the requested namespace is written
+		err = fakeClient.Get(t.Context(), client.ObjectKey{Namespace: "foo", Name: source.Name}, &replica)
+		assert.True(t, apierrors.IsNotFound(err))
+
+		// the namespace is added to the recorded status
+		var actual corev1.ConfigMap
+		require.NoError(t, fakeClient.Get(t.Context(), client.ObjectKeyFromObject(source), &actual))
+		assert.Equal(t, "bar,foo", actual.Annotations[replicator.ReplicatedToAnnotation])
+	})
+
+	t.Run("failed namespace", func(t *testing.T) {
+		forbidden := errors.New("forbidden")
+		fakeClient := fake.NewClientBuilder().
+			WithObjects(source.DeepCopy()).
+			WithInterceptorFuncs(interceptor.Funcs{
+				Create: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.CreateOption) error {
+					return forbidden
+				},
+			}).
+			Build()
+		r, _ := newReconciler()
+		r.client = fakeClient
+		r.replicator = replicator.New[*corev1.ConfigMap](fakeClient, fakeClient, r.config, r.recorder)
+
+		_, err := r.reconcileNamespace(t.Context(), namespaceRequest{
+			Source:    client.ObjectKeyFromObject(source),
+			Namespace: "bar",
+		})
+		assert.ErrorIs(t, err, forbidden)
+
+		var actual corev1.ConfigMap
+		require.NoError(t, fakeClient.Get(t.Context(), client.ObjectKeyFromObject(source), &actual))
+		assert.Equal(t, "foo", actual.Annotations[replicator.ReplicatedToAnnotation])
+		assert.Equal(t, "bar", actual.Annotations[replicator.FailedNamespacesAnnotation])
+		assert.Equal(t, `{"bar":"create or updating replica: forbidden"}`,
+			actual.Annotations[replicator.ReplicationErrorsAnnotation])
+	})
+
+	t.Run("namespace not indexed yet", func(t *testing.T) {
+		r, fakeClient := newReconciler(source.DeepCopy(), &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "baz"}})
+
+		_, err := r.reconcileNamespace(t.Context(), namespaceRequest{
+			Source:    client.ObjectKeyFromObject(source),
+			Namespace: "baz",
+		})
+		require.NoError(t, err)
+
+		err = fakeClient.Get(t.Context(), client.ObjectKey{Namespace: "baz", Name: source.Name}, &corev1.ConfigMap{})
+		require.NoError(t, err)
+	})
+
+	t.Run("recorded namespace", func(t *testing.T) {
+		r, fakeClient := newReconciler(source.DeepCopy())
+		var before corev1.ConfigMap
+		require.NoError(t, fakeClient.Get(t.Context(), client.ObjectKeyFromObject(source), &before))
+
+		_, err := r.reconcileNamespace(t.Context(), namespaceRequest{
+			Source:    client.ObjectKeyFromObject(source),
+			Namespace: "foo",
+		})
+		require.NoError(t, err)
+
+		// the unchanged status is not written
+		var actual corev1.ConfigMap
+		require.NoError(t, fakeClient.Get(t.Context(), client.ObjectKeyFromObject(source), &actual))
+		assert.Equal(t, before.ResourceVersion, actual.ResourceVersion)
+	})
+
+	t.Run("deleted namespace", func(t *testing.T) {
+		r, fakeClient := newReconciler(source.DeepCopy())
+		r.namespaces.Delete("foo")
+
+		_, err := r.reconcileNamespace(t.Context(), namespaceRequest{
+			Source:    client.ObjectKeyFromObject(source),
+			Namespace: "foo",
+		})
+		require.NoError(t, err)
+
+		// the namespace is removed from the recorded status
+		var actual corev1.ConfigMap
+		require.NoError(t, fakeClient.Get(t.Context(), client.ObjectKeyFromObject(source), &actual))
+		assert.NotContains(t, actual.Annotations, replicator.ReplicatedToAnnotation)
+	})
+
+	t.Run("terminating namespace", func(t *testing.T) {
+		r, fakeClient := newReconciler(source.DeepCopy())
+		r.namespaces.Set(namespaces.Namespace{Name: "bar", Phase: corev1.NamespaceTerminating})
+
+		_, err := r.reconcileNamespace(t.Context(), namespaceRequest{
+			Source:    client.ObjectKeyFromObject(source),
+			Namespace: "bar",
+		})
+		require.NoError(t, err)
+
+		var replicaList corev1.ConfigMapList
+		require.NoError(t, fakeClient.List(t.Context(), &replicaList, client.HasLabels{replicator.SourceNameLabel}))
+		assert.Empty(t, replicaList.Items)
+	})
+
+	t.Run("source namespace", func(t *testing.T) {
+		r, fakeClient := newReconciler(source.DeepCopy())
+
+		_, err := r.reconcileNamespace(t.Context(), namespaceRequest{
+			Source:    client.ObjectKeyFromObject(source),
+			Namespace: source.Namespace,
+		})
+		require.NoError(t, err)
+
+		var replicaList corev1.ConfigMapList
+		require.NoError(t, fakeClient.List(t.Context(), &replicaList, client.HasLabels{replicator.SourceNameLabel}))
+		assert.Empty(t, replicaList.Items)
+	})
+
+	t.Run("source without finalizer", func(t *testing.T) {
+		uninitialized := source.DeepCopy()
+		uninitialized.Finalizers = nil
+		r, fakeClient := newReconciler(uninitialized)
+
+		_, err := r.reconcileNamespace(t.Context(), namespaceRequest{
+			Source:    client.ObjectKeyFromObject(source),
+			Namespace: "bar",
+		})
+		require.NoError(t, err)
+
+		var replicaList corev1.ConfigMapList
+		require.NoError(t, fakeClient.List(t.Context(), &replicaList, client.HasLabels{replicator.SourceNameLabel}))
+		assert.Empty(t, replicaList.Items)
+	})
+}
+
+func TestReconciler_Reconcile_retry(t *testing.T) {
+	source := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "source-name",
+			Namespace:   "source-namespace",
+			Finalizers:  []string{sourceFinalizer},
+			Annotations: map[string]string{replicator.ReplicationAllowedAnnotation: "true"},
+		},
+	}
+	conflict := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: source.Name, Namespace: "baz"}}
+
+	forbidden := errors.New("forbidden")
+	fakeClient := fake.NewClientBuilder().
+		WithObjects(source, conflict).
+		WithInterceptorFuncs(interceptor.Funcs{
+			Create: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.CreateOption) error {
+				if obj.GetNamespace() == "bar" {
+					return forbidden
+				}
+				return c.Create(ctx, obj, opts...)
+			},
+		}).
+		Build()
+	r := NewReconciler[*corev1.ConfigMap](
+		fakeClient,
+		fakeClient,
+		namespaces.NewIndex(
+			namespaces.Namespace{Name: "foo"},
+			namespaces.Namespace{Name: "bar"},
+			namespaces.Namespace{Name: "baz"},
+		),
+		&config.Config{FanoutConcurrency: 1},
+		&record.FakeRecorder{},
+		replicator.EmptyConfigMap,
+		replicator.EmptyConfigMapList,
+	)
+	r.retries = make(chan event.TypedGenericEvent[namespaceRequest], 3)
+
+	_, err := r.Reconcile(t.Context(), reconcile.Request{NamespacedName: client.ObjectKeyFromObject(source)})
+	require.NoError(t, err)
+
+	// only the failed namespace is retried, conflicts are not resolved by retrying
+	require.Len(t, r.retries, 1)
+	assert.Equal(t, namespaceRequest{Source: client.ObjectKeyFromObject(source), Namespace: "bar"}, (<-r.retries).Object)
+
+	var actual corev1.ConfigMap
+	require.NoError(t, fakeClient.Get(t.Context(), client.ObjectKeyFromObject(source), &actual))
+	assert.Equal(t, "foo", actual.Annotations[replicator.ReplicatedToAnnotation])
+	assert.Equal(t, "bar,baz", actual.Annotations[replicator.FailedNamespacesAnnotation])
+}
+
+func TestRecordedOutcome(t *testing.T) {
+	source := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{
+		replicator.ReplicatedToAnnotation:      "bar,foo",
+		replicator.FailedNamespacesAnnotation:  "baz,testing",
+		replicator.ReplicationErrorsAnnotation: `{"baz":"conflict","testing":"forbidden"}`,
+		replicator.ConflictsAnnotation:         "baz",
+	}}}
+
+	o := recordedOutcome(source)
+	assert.Equal(t, source.Annotations, o.annotations())
+
+	status := newReplicationStatus()
+	status.succeeded("testing")
+	o.merge("testing", status)
+	o.merge("foo", newReplicationStatus())
+
+	assert.Equal(t, map[string]string{
+		replicator.ReplicatedToAnnotation:      "bar,testing",
+		replicator.FailedNamespacesAnnotation:  "baz",
+		replicator.ReplicationErrorsAnnotation: `{"baz":"conflict"}`,
+		replicator.ConflictsAnnotation:         "baz",
+	}, o.annotations())
+}
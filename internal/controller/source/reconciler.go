@@ -2,83 +2,217 @@ package source
 
 import (
 	"context"
+	"errors"
+	"slices"
 	"time"
 
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	"github.com/c0deltin/replik8or/internal/config"
+	"github.com/c0deltin/replik8or/internal/controller"
+	"github.com/c0deltin/replik8or/internal/namespaces"
 	"github.com/c0deltin/replik8or/internal/replicator"
+	"github.com/c0deltin/replik8or/internal/tracing"
 )
 
+var tracer = otel.Tracer("github.com/c0deltin/replik8or/internal/controller/source")
+
 type Reconciler[T client.Object] struct {
-	client client.Client
-	config *config.Config
+	kind       string
+	client     client.Client
+	namespaces *namespaces.Index
+	config     *config.Config
+	recorder   record.EventRecorder
 
 	emptyObjectFn     func() T
 	emptyObjectListFn func() client.ObjectList
 
 	replicator *replicator.Replicator[T]
+	metrics    *sourceMetrics
+	requeue    chan event.GenericEvent
+	// retries requests the replication into a single namespace which failed, see retry.
+	retries chan event.TypedGenericEvent[namespaceRequest]
 }
 
 func NewReconciler[T client.Object](
 	client client.Client,
+	apiReader client.Reader,
+	namespaces *namespaces.Index,
 	config *config.Config,
+	recorder record.EventRecorder,
 	emptyObjectFn func() T,
 	emptyObjectListFn func() client.ObjectList,
 ) *Reconciler[T] {
+	kind := replicator.Kind(emptyObjectFn())
 	return &Reconciler[T]{
+		kind:              kind,
 		client:            client,
+		namespaces:        namespaces,
 		config:            config,
+		recorder:          recorder,
 		emptyObjectFn:     emptyObjectFn,
 		emptyObjectListFn: emptyObjectListFn,
-		replicator:        replicator.New[T](client, config),
+		replicator:        replicator.New[T](client, apiReader, config, recorder),
+		metrics:           newSourceMetrics(kind),
+		requeue:           make(chan event.GenericEvent),
+		retries:           make(chan event.TypedGenericEvent[namespaceRequest]),
 	}
 }
 
-func (r *Reconciler[T]) Reconcile(ctx context.Context, req reconcile.Request) (reconcile.Result, error) {
+// Requeue returns a channel to trigger the reconciliation of a source from outside the controller.
+func (r *Reconciler[T]) Requeue() chan<- event.GenericEvent {
+	return r.requeue
+}
+
+func (r *Reconciler[T]) Reconcile(ctx context.Context, req reconcile.Request) (result reconcile.Result, err error) {
+	ctx, span := tracer.Start(ctx, "Reconciler.Reconcile", trace.WithAttributes(
+		tracing.SourceKey.String(req.String()),
+		tracing.KindKey.String(r.kind),
+	))
+	defer func() { tracing.End(span, err) }()
+
 	var source = r.emptyObjectFn()
 	if err := r.client.Get(ctx, req.NamespacedName, source); err != nil {
+		if apierrors.IsNotFound(err) {
+			r.metrics.forget(req.NamespacedName)
+			r.replicator.Forget(req.NamespacedName)
+		}
 		return reconcile.Result{}, client.IgnoreNotFound(err)
 	}
 
-	if !source.GetDeletionTimestamp().IsZero() {
+	if !r.isReplicated(source) {
 		return r.finalizeAndDelete(ctx, source)
 	}
 
-	if controllerutil.AddFinalizer(source, sourceFinalizer) {
+	// in dry-run mode the source is never touched, so no finalizer is added
+	if !r.config.DryRun && controllerutil.AddFinalizer(source, sourceFinalizer) {
 		if err := r.client.Update(ctx, source); err != nil {
 			return reconcile.Result{}, err
 		}
 		return reconcile.Result{RequeueAfter: time.Second}, nil
 	}
 
-	targetNamespaces, err := r.replicator.ListTargetNamespaces(ctx, source)
+	targetNamespaces, err := r.replicator.ListTargetNamespaces(ctx, source, r.namespaces)
 	if err != nil {
 		return reconcile.Result{}, err
 	}
 
-	for _, targetNamespace := range targetNamespaces {
-		var replica = r.emptyObjectFn()
-		replica.SetName(source.GetName())
-		replica.SetNamespace(targetNamespace)
+	status := newReplicationStatus()
+	controller.FanOut(r.config.FanoutConcurrency, targetNamespaces, func(targetNamespace string) {
+		r.replicate(ctx, source, targetNamespace, status)
+	})
+	r.metrics.observe(req.NamespacedName, len(status.replicatedTo))
+
+	// the event is only recorded when the replicated namespaces changed, not on every reconciliation. In dry-run mode
+	// the status is never written, so the change can not be detected and the writes are reported as DryRun Events.
+	replicationErr := status.err()
+	if replicationErr == nil && !r.config.DryRun &&
+		status.outcome().annotations()[replicator.ReplicatedToAnnotation] !=
+			source.GetAnnotations()[replicator.ReplicatedToAnnotation] {
+		r.recorder.Eventf(source, corev1.EventTypeNormal, replicator.EventReasonReplicated,
+			"Replicated to %d namespaces", len(status.replicatedTo))
+	}
+	if err := r.updateStatus(ctx, source, status); err != nil {
+		return reconcile.Result{}, err
+	}
 
-		if err := r.replicator.CreateOrUpdate(ctx, source, replica); err != nil {
-			return reconcile.Result{}, err
+	// only the failed namespaces are retried with backoff, the replicas in all other namespaces are in place and not
+	// written again until the source changes
+	if replicationErr != nil {
+		log.FromContext(ctx).Error(replicationErr, "replication failed, retrying the failed namespaces")
+		return reconcile.Result{}, r.retry(ctx, source, status)
+	}
+	return reconcile.Result{}, nil
+}
+
+// retry requests the replication of source into each namespace which failed in status. The requests are handled by
+// the namespace controller, which retries every namespace on its own with backoff until it succeeded.
+func (r *Reconciler[T]) retry(ctx context.Context, source T, status *replicationStatus) error {
+	for _, namespace := range status.retried() {
+		req := namespaceRequest{Source: client.ObjectKeyFromObject(source), Namespace: namespace}
+		select {
+		case r.retries <- event.TypedGenericEvent[namespaceRequest]{Object: req}:
+		case <-ctx.Done():
+			return ctx.Err()
 		}
 	}
+	return nil
+}
 
-	return reconcile.Result{}, nil
+// replicate writes the replica of source into targetNamespace and records the outcome in status.
+func (r *Reconciler[T]) replicate(ctx context.Context, source T, targetNamespace string, status *replicationStatus) {
+	var replica = r.emptyObjectFn()
+	replica.SetName(source.GetName())
+	replica.SetNamespace(targetNamespace)
+
+	if err := r.replicator.CreateOrUpdate(ctx, source, replica); err != nil {
+		if errors.Is(err, replicator.ErrNamespaceTerminating) || errors.Is(err, replicator.ErrNamespaceNotFound) {
+			// the namespace is no longer targeted once it was removed, or targeted again once it was created
+			return
+		}
+		if errors.Is(err, replicator.ErrOverridden) {
+			r.recorder.Eventf(source, corev1.EventTypeNormal, replicator.EventReasonOverridden,
+				"Not replicated to namespace %s: %s is managed by a policy", targetNamespace,
+				replicator.NamespacedName(replica))
+			return
+		}
+		status.failed(targetNamespace, err)
+		if errors.Is(err, replicator.ErrConflict) {
+			r.recorder.Eventf(source, corev1.EventTypeWarning, replicator.EventReasonConflict,
+				"Not replicated to namespace %s: %s already exists and is not a replica of this source",
+				targetNamespace, replicator.NamespacedName(replica))
+			return
+		}
+		r.recorder.Eventf(source, corev1.EventTypeWarning, replicator.EventReasonReplicationFailed,
+			"Replication to namespace %s failed: %v", targetNamespace, err)
+		return
+	}
+	status.succeeded(targetNamespace)
 }
 
-func (r *Reconciler[T]) finalizeAndDelete(ctx context.Context, source client.Object) (reconcile.Result, error) {
-	var replicaList = r.emptyObjectListFn()
-	if err := r.client.List(ctx, replicaList, client.MatchingLabels{
+// isReplicated reports whether source is replicated. Sources which are no longer annotated or not allowed to be
+// replicated are cleaned up like deleted ones.
+func (r *Reconciler[T]) isReplicated(source T) bool {
+	return source.GetDeletionTimestamp().IsZero() &&
+		replicator.HasAnnotations(source, replicator.ReplicationAllowedAnnotation) &&
+		!slices.Contains(r.config.DisallowedSourceNamespaces, source.GetNamespace())
+}
+
+func (r *Reconciler[T]) finalizeAndDelete(ctx context.Context, source client.Object) (result reconcile.Result, err error) {
+	ctx, span := tracer.Start(ctx, "Reconciler.finalizeAndDelete",
+		trace.WithAttributes(tracing.SourceAttributes(source, r.kind)...))
+	defer func() { tracing.End(span, err) }()
+
+	// replicas managed by a policy are not touched
+	selector, err := labels.ValidatedSelectorFromSet(labels.Set{
 		replicator.SourceNameLabel:      source.GetName(),
 		replicator.SourceNamespaceLabel: source.GetNamespace(),
-	}); err != nil {
+	})
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	for _, label := range []string{replicator.PolicyLabel, replicator.ClusterPolicyLabel} {
+		withoutPolicy, err := labels.NewRequirement(label, selection.DoesNotExist, nil)
+		if err != nil {
+			return reconcile.Result{}, err
+		}
+		selector = selector.Add(*withoutPolicy)
+	}
+
+	var replicaList = r.emptyObjectListFn()
+	if err := r.client.List(ctx, replicaList, client.MatchingLabelsSelector{Selector: selector}); err != nil {
 		return reconcile.Result{}, err
 	}
 
@@ -88,16 +222,28 @@ func (r *Reconciler[T]) finalizeAndDelete(ctx context.Context, source client.Obj
 	}
 
 	for _, replica := range replicas {
-		if err := r.client.Delete(ctx, replica.(client.Object)); err != nil {
+		if err := r.replicator.Delete(ctx, source, replica.(client.Object)); err != nil {
 			return reconcile.Result{}, err
 		}
 	}
 
-	if controllerutil.RemoveFinalizer(source, sourceFinalizer) {
-		if err := r.client.Update(ctx, source); err != nil {
+	// in dry-run mode the source is never written. A finalizer added before dry-run was enabled therefore remains and
+	// blocks the deletion of the source until dry-run is disabled again.
+	changed := controllerutil.RemoveFinalizer(source, sourceFinalizer)
+	if source.GetDeletionTimestamp().IsZero() {
+		changed = clearStatus(source) || changed
+	}
+	if changed {
+		var opts []client.UpdateOption
+		if r.config.DryRun {
+			opts = append(opts, client.DryRunAll)
+		}
+		if err := r.client.Update(ctx, source, opts...); err != nil {
 			return reconcile.Result{}, err
 		}
 	}
+	r.metrics.forget(client.ObjectKeyFromObject(source))
+	r.replicator.Forget(client.ObjectKeyFromObject(source))
 
 	return reconcile.Result{}, nil
 }
@@ -55,6 +55,18 @@ var _ = Describe("SourceReconciler reconciles corev1.ConfigMap", Ordered, func()
 			}).Should(Succeed())
 		})
 
+		It("should write the replication status to the source", func() {
+			Eventually(func(g Gomega) {
+				var source corev1.ConfigMap
+				err := k8sClient.Get(ctx, ctrlclient.ObjectKeyFromObject(sourceConfigMap), &source)
+				g.Expect(err).NotTo(HaveOccurred())
+
+				g.Expect(source.Annotations).To(HaveKeyWithValue(replicator.ReplicatedToAnnotation, "bar,foo,testing"))
+				g.Expect(source.Annotations).NotTo(HaveKey(replicator.ReplicationErrorsAnnotation))
+				g.Expect(source.Annotations).To(HaveKey(replicator.LastReplicationAnnotation))
+			}).WithTimeout(5 * time.Second).Should(Succeed())
+		})
+
 		It("should recreate a replica when it was deleted", func() {
 			var replica corev1.ConfigMap
 
@@ -0,0 +1,285 @@
+package source
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/c0deltin/replik8or/internal/replicator"
+)
+
+const (
+	// maxRecordedErrors is the number of namespaces whose error is recorded in the ReplicationErrorsAnnotation, all
+	// failed namespaces are listed in the FailedNamespacesAnnotation.
+	maxRecordedErrors = 10
+	// maxErrorLength is the length in bytes an error message is truncated to in the ReplicationErrorsAnnotation.
+	maxErrorLength = 512
+)
+
+var statusAnnotations = []string{
+	replicator.ReplicatedToAnnotation,
+	replicator.FailedNamespacesAnnotation,
+	replicator.ReplicationErrorsAnnotation,
+	replicator.ConflictsAnnotation,
+	replicator.LastReplicationAnnotation,
+}
+
+// replicationStatus is the outcome of replicating a source into its target namespaces. It is safe for concurrent
+// use, as the namespaces are replicated in parallel.
+type replicationStatus struct {
+	mu           sync.Mutex
+	replicatedTo []string
+	errors       map[string]error
+}
+
+func newReplicationStatus() *replicationStatus {
+	return &replicationStatus{errors: map[string]error{}}
+}
+
+func (s *replicationStatus) succeeded(namespace string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.replicatedTo = append(s.replicatedTo, namespace)
+}
+
+func (s *replicationStatus) failed(namespace string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.errors[namespace] = err
+}
+
+// err joins the errors of all failed namespaces. Conflicts are reported by status only as retrying will not resolve
+// them.
+func (s *replicationStatus) err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var errs []error
+	for _, namespace := range slices.Sorted(maps.Keys(s.errors)) {
+		if err := s.errors[namespace]; !errors.Is(err, replicator.ErrConflict) {
+			errs = append(errs, fmt.Errorf("namespace %s: %w", namespace, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// retried returns the failed namespaces which are retried. Conflicts are not retried, see err.
+func (s *replicationStatus) retried() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var namespaces []string
+	for _, namespace := range slices.Sorted(maps.Keys(s.errors)) {
+		if !errors.Is(s.errors[namespace], replicator.ErrConflict) {
+			namespaces = append(namespaces, namespace)
+		}
+	}
+	return namespaces
+}
+
+// outcome returns the outcome of the replication as recorded in the status annotations.
+func (s *replicationStatus) outcome() outcome {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	o := newOutcome()
+	for _, namespace := range s.replicatedTo {
+		o.replicatedTo[namespace] = struct{}{}
+	}
+	for namespace, err := range s.errors {
+		o.errors[namespace] = err.Error()
+		if errors.Is(err, replicator.ErrConflict) {
+			o.conflicts[namespace] = struct{}{}
+		}
+	}
+	return o
+}
+
+// outcome is the replication outcome recorded in the status annotations of a source. Unlike replicationStatus, it
+// is read back from the source, so the outcome of a single namespace can be changed without replicating all others.
+type outcome struct {
+	replicatedTo map[string]struct{}
+	conflicts    map[string]struct{}
+	// errors holds the message per failed namespace.
+	errors map[string]string
+}
+
+func newOutcome() outcome {
+	return outcome{
+		replicatedTo: map[string]struct{}{},
+		conflicts:    map[string]struct{}{},
+		errors:       map[string]string{},
+	}
+}
+
+// recordedOutcome returns the outcome recorded in the status annotations of source. The errors of a source written
+// by a previous version are not structured and therefore ignored, they are replaced by the next reconciliation.
+func recordedOutcome(source client.Object) outcome {
+	annotations := source.GetAnnotations()
+	o := newOutcome()
+	for _, namespace := range splitNamespaces(annotations[replicator.ReplicatedToAnnotation]) {
+		o.replicatedTo[namespace] = struct{}{}
+	}
+	for _, namespace := range splitNamespaces(annotations[replicator.ConflictsAnnotation]) {
+		o.conflicts[namespace] = struct{}{}
+	}
+	_ = json.Unmarshal([]byte(annotations[replicator.ReplicationErrorsAnnotation]), &o.errors)
+	for _, namespace := range splitNamespaces(annotations[replicator.FailedNamespacesAnnotation]) {
+		if _, ok := o.errors[namespace]; !ok {
+			o.errors[namespace] = ""
+		}
+	}
+	return o
+}
+
+// merge replaces the recorded outcome of namespace with the one in status. A namespace missing in status, e.g.
+// because it was removed, is removed from the outcome.
+func (o outcome) merge(namespace string, status *replicationStatus) {
+	delete(o.replicatedTo, namespace)
+	delete(o.conflicts, namespace)
+	delete(o.errors, namespace)
+
+	update := status.outcome()
+	if _, ok := update.replicatedTo[namespace]; ok {
+		o.replicatedTo[namespace] = struct{}{}
+	}
+	if _, ok := update.conflicts[namespace]; ok {
+		o.conflicts[namespace] = struct{}{}
+	}
+	if message, ok := update.errors[namespace]; ok {
+		o.errors[namespace] = message
+	}
+}
+
+// annotations returns the status annotations describing the outcome. Annotations are limited in size, so only the
+// errors of the first namespaces are recorded and truncated, the complete errors are reported by Events and logs.
+func (o outcome) annotations() map[string]string {
+	recorded := map[string]string{}
+	for _, namespace := range slices.Sorted(maps.Keys(o.errors)) {
+		// the errors of namespaces which were not recorded before are unknown
+		if message := o.errors[namespace]; message != "" && len(recorded) < maxRecordedErrors {
+			recorded[namespace] = truncate(message, maxErrorLength)
+		}
+	}
+
+	var errs string
+	if len(recorded) > 0 {
+		// encoding a map of strings never fails, the keys are sorted
+		encoded, _ := json.Marshal(recorded)
+		errs = string(encoded)
+	}
+
+	return map[string]string{
+		replicator.ReplicatedToAnnotation:      strings.Join(slices.Sorted(maps.Keys(o.replicatedTo)), ","),
+		replicator.FailedNamespacesAnnotation:  strings.Join(slices.Sorted(maps.Keys(o.errors)), ","),
+		replicator.ReplicationErrorsAnnotation: errs,
+		replicator.ConflictsAnnotation:         strings.Join(slices.Sorted(maps.Keys(o.conflicts)), ","),
+	}
+}
+
+// truncate shortens s to at most n bytes without splitting a character.
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	const ellipsis = "..."
+	s = s[:n-len(ellipsis)]
+	for !utf8.ValidString(s) {
+		s = s[:len(s)-1]
+	}
+	return s + ellipsis
+}
+
+// splitNamespaces splits a comma separated list of namespaces.
+func splitNamespaces(value string) []string {
+	if value == "" {
+		return nil
+	}
+	return strings.Split(value, ",")
+}
+
+// updateStatus writes the status annotations describing status to the source.
+func (r *Reconciler[T]) updateStatus(ctx context.Context, source T, status *replicationStatus) error {
+	return r.patchStatus(ctx, source, status.outcome())
+}
+
+// updateNamespaceStatus writes the outcome of replicating source into namespace to the status annotations of the
+// source, leaving the recorded outcome of all other namespaces untouched.
+func (r *Reconciler[T]) updateNamespaceStatus(
+	ctx context.Context,
+	source T,
+	namespace string,
+	status *replicationStatus,
+) error {
+	o := recordedOutcome(source)
+	o.merge(namespace, status)
+	return r.patchStatus(ctx, source, o)
+}
+
+// patchStatus writes the status annotations of o to the source. The source is only patched when the outcome changed,
+// as every write to the source triggers another reconciliation. The namespaces of a source are reconciled
+// concurrently, so the patch fails if the source changed in the meantime instead of dropping the outcome of another
+// namespace.
+func (r *Reconciler[T]) patchStatus(ctx context.Context, source T, o outcome) error {
+	if r.config.DryRun {
+		return nil
+	}
+
+	desired := o.annotations()
+	current := source.GetAnnotations()
+
+	var changed bool
+	for key, value := range desired {
+		if current[key] != value {
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+
+	patch := client.MergeFromWithOptions(source.DeepCopyObject().(T), client.MergeFromWithOptimisticLock{})
+
+	annotations := maps.Clone(current)
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	for key, value := range desired {
+		if value == "" {
+			delete(annotations, key)
+			continue
+		}
+		annotations[key] = value
+	}
+	annotations[replicator.LastReplicationAnnotation] = time.Now().UTC().Format(time.RFC3339)
+	source.SetAnnotations(annotations)
+
+	return r.client.Patch(ctx, source, patch)
+}
+
+// clearStatus removes the status annotations from a source which is no longer replicated and reports whether any
+// annotation was removed.
+func clearStatus(source client.Object) bool {
+	annotations := maps.Clone(source.GetAnnotations())
+
+	var removed bool
+	for _, key := range statusAnnotations {
+		if _, ok := annotations[key]; ok {
+			delete(annotations, key)
+			removed = true
+		}
+	}
+	if removed {
+		source.SetAnnotations(annotations)
+	}
+	return removed
+}
@@ -0,0 +1,132 @@
+package source
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/c0deltin/replik8or/internal/config"
+	"github.com/c0deltin/replik8or/internal/replicator"
+)
+
+func TestReplicationStatus_outcome(t *testing.T) {
+	status := newReplicationStatus()
+	status.succeeded("foo")
+	status.succeeded("bar")
+	status.failed("testing", errors.New("forbidden"))
+	status.failed("baz", replicator.ErrConflict)
+
+	expected := map[string]string{
+		replicator.ReplicatedToAnnotation:     "bar,foo",
+		replicator.FailedNamespacesAnnotation: "baz,testing",
+		replicator.ReplicationErrorsAnnotation: `{"baz":"` + replicator.ErrConflict.Error() +
+			`","testing":"forbidden"}`,
+		replicator.ConflictsAnnotation: "baz",
+	}
+
+	assert.Equal(t, expected, status.outcome().annotations())
+}
+
+func TestOutcome_annotations(t *testing.T) {
+	status := newReplicationStatus()
+	for i := range maxRecordedErrors + 2 {
+		status.failed(fmt.Sprintf("namespace-%02d", i), errors.New(strings.Repeat("ä", maxErrorLength)))
+	}
+
+	annotations := status.outcome().annotations()
+
+	var recorded map[string]string
+	require.NoError(t, json.Unmarshal([]byte(annotations[replicator.ReplicationErrorsAnnotation]), &recorded))
+	assert.Len(t, recorded, maxRecordedErrors)
+	assert.NotContains(t, recorded, "namespace-11")
+	for _, message := range recorded {
+		assert.LessOrEqual(t, len(message), maxErrorLength)
+		assert.True(t, utf8.ValidString(message))
+		assert.True(t, strings.HasSuffix(message, "..."))
+	}
+
+	// all failed namespaces are listed
+	assert.Len(t, strings.Split(annotations[replicator.FailedNamespacesAnnotation], ","), maxRecordedErrors+2)
+}
+
+func TestReplicationStatus_err(t *testing.T) {
+	status := newReplicationStatus()
+	status.succeeded("foo")
+	assert.NoError(t, status.err())
+
+	forbidden := errors.New("forbidden")
+	status.failed("testing", forbidden)
+	status.failed("bar", forbidden)
+	status.failed("baz", replicator.ErrConflict)
+
+	err := status.err()
+	assert.ErrorIs(t, err, forbidden)
+	assert.NotErrorIs(t, err, replicator.ErrConflict)
+	assert.Equal(t, "namespace bar: forbidden\nnamespace testing: forbidden", err.Error())
+}
+
+func TestReconciler_updateStatus(t *testing.T) {
+	source := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "source-name",
+			Namespace: "source-namespace",
+			Annotations: map[string]string{
+				replicator.ReplicationAllowedAnnotation: "true",
+			},
+		},
+	}
+
+	t.Run("changed status", func(t *testing.T) {
+		fakeClient := fake.NewFakeClient(source.DeepCopy())
+		r := Reconciler[*corev1.ConfigMap]{client: fakeClient, config: &config.Config{}}
+
+		var actual corev1.ConfigMap
+		err := fakeClient.Get(t.Context(), client.ObjectKeyFromObject(source), &actual)
+		assert.NoError(t, err)
+
+		status := newReplicationStatus()
+		status.succeeded("foo")
+		err = r.updateStatus(t.Context(), &actual, status)
+		assert.NoError(t, err)
+
+		err = fakeClient.Get(t.Context(), client.ObjectKeyFromObject(source), &actual)
+		assert.NoError(t, err)
+		assert.Equal(t, "foo", actual.Annotations[replicator.ReplicatedToAnnotation])
+		assert.Equal(t, "true", actual.Annotations[replicator.ReplicationAllowedAnnotation])
+		assert.NotContains(t, actual.Annotations, replicator.ReplicationErrorsAnnotation)
+		assert.Contains(t, actual.Annotations, replicator.LastReplicationAnnotation)
+	})
+
+	t.Run("unchanged status", func(t *testing.T) {
+		existing := source.DeepCopy()
+		existing.Annotations[replicator.ReplicatedToAnnotation] = "foo"
+		existing.Annotations[replicator.LastReplicationAnnotation] = "2006-01-02T15:04:05Z"
+		fakeClient := fake.NewFakeClient(existing)
+		r := Reconciler[*corev1.ConfigMap]{client: fakeClient, config: &config.Config{}}
+
+		var actual corev1.ConfigMap
+		err := fakeClient.Get(t.Context(), client.ObjectKeyFromObject(source), &actual)
+		assert.NoError(t, err)
+		resourceVersion := actual.ResourceVersion
+
+		status := newReplicationStatus()
+		status.succeeded("foo")
+		err = r.updateStatus(t.Context(), &actual, status)
+		assert.NoError(t, err)
+
+		err = fakeClient.Get(t.Context(), client.ObjectKeyFromObject(source), &actual)
+		assert.NoError(t, err)
+		assert.Equal(t, resourceVersion, actual.ResourceVersion)
+		assert.Equal(t, "2006-01-02T15:04:05Z", actual.Annotations[replicator.LastReplicationAnnotation])
+	})
+}
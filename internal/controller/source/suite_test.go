@@ -8,6 +8,7 @@ import (
 	"testing"
 
 	"github.com/c0deltin/replik8or/internal/config"
+	"github.com/c0deltin/replik8or/internal/namespaces"
 	"github.com/c0deltin/replik8or/internal/replicator"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -80,9 +81,15 @@ var _ = BeforeSuite(func() {
 	k8sManager, err := ctrl.NewManager(restCfg, ctrl.Options{Scheme: scheme.Scheme})
 	Expect(err).NotTo(HaveOccurred())
 
+	index := namespaces.NewIndex()
+	Expect(index.SetupWithManager(ctx, k8sManager)).To(Succeed())
+
 	err = NewReconciler[*corev1.ConfigMap](
 		k8sClient,
+		k8sClient,
+		index,
 		&config.Config{DisallowedNamespaces: systemNamespaces},
+		k8sManager.GetEventRecorderFor("replik8or"), //nolint:staticcheck
 		replicator.EmptyConfigMap,
 		replicator.EmptyConfigMapList,
 	).SetupWithManager("ConfigMap", k8sManager)
@@ -0,0 +1,249 @@
+package staleness
+
+import (
+	"context"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/c0deltin/replik8or/internal/config"
+	"github.com/c0deltin/replik8or/internal/controller/orphan"
+	"github.com/c0deltin/replik8or/internal/replicator"
+)
+
+// Requeue holds the channels triggering the reconciliation of the sources and policies replicating stale replicas.
+// Nil channels are skipped, e.g. if policies are disabled.
+type Requeue struct {
+	Sources         chan<- event.GenericEvent
+	Policies        chan<- event.GenericEvent
+	ClusterPolicies chan<- event.GenericEvent
+}
+
+// Checker periodically compares the replicas with their sources and reports replicas which were not updated after
+// their source changed.
+type Checker[T client.Object] struct {
+	client   client.Client
+	config   *config.Config
+	recorder record.EventRecorder
+	// apiReader reads the sources of policies, which are cached without their data unless they are annotated
+	apiReader client.Reader
+
+	emptyObjectFn     func() T
+	emptyObjectListFn func() client.ObjectList
+
+	orphans *orphan.Collector[T]
+	requeue Requeue
+}
+
+func NewChecker[T client.Object](
+	client client.Client,
+	apiReader client.Reader,
+	config *config.Config,
+	recorder record.EventRecorder,
+	emptyObjectFn func() T,
+	emptyObjectListFn func() client.ObjectList,
+	orphans *orphan.Collector[T],
+	requeue Requeue,
+) *Checker[T] {
+	return &Checker[T]{
+		client:            client,
+		apiReader:         apiReader,
+		config:            config,
+		recorder:          recorder,
+		emptyObjectFn:     emptyObjectFn,
+		emptyObjectListFn: emptyObjectListFn,
+		orphans:           orphans,
+		requeue:           requeue,
+	}
+}
+
+// Start runs the check in the configured interval until ctx is done.
+func (c *Checker[T]) Start(ctx context.Context) error {
+	lgr := log.FromContext(ctx).WithName("staleness").WithValues("kind", replicator.Kind(c.emptyObjectFn()))
+
+	ticker := time.NewTicker(c.config.StalenessCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			stale, err := c.check(ctx)
+			if err != nil {
+				lgr.Error(err, "checking replicas for staleness")
+				continue
+			}
+			if stale > 0 {
+				lgr.Info("found stale replicas", "count", stale)
+			}
+		}
+	}
+}
+
+// NeedLeaderElection makes sure only the leader reports stale replicas.
+func (c *Checker[T]) NeedLeaderElection() bool {
+	return true
+}
+
+// check lists all replicas and returns the number of stale ones.
+func (c *Checker[T]) check(ctx context.Context) (int, error) {
+	var replicaList = c.emptyObjectListFn()
+	if err := c.client.List(ctx, replicaList, client.HasLabels{
+		replicator.SourceNamespaceLabel,
+		replicator.SourceNameLabel,
+	}); err != nil {
+		return 0, err
+	}
+
+	replicas, err := meta.ExtractList(replicaList)
+	if err != nil {
+		return 0, err
+	}
+
+	var (
+		stale   int
+		sources = NewSources(c)
+		// owners are the sources and policies of the stale replicas per requeue channel
+		owners = map[chan<- event.GenericEvent]map[client.ObjectKey]client.Object{}
+	)
+	for _, object := range replicas {
+		replica := object.(client.Object)
+
+		// replicas without source are orphans and not stale, replicas of unreadable sources are skipped
+		source, ok, err := sources.Get(ctx, replica)
+		if err != nil {
+			// e.g. the namespace of the source is not watched, which must not fail the check of others
+			log.FromContext(ctx).Error(err, "skipping replicas of unreadable source", "source", sourceKey(replica))
+			continue
+		}
+		if !ok {
+			continue
+		}
+		isStale, err := c.IsStale(ctx, replica, source)
+		if err != nil {
+			return 0, err
+		}
+		if !isStale {
+			continue
+		}
+
+		stale++
+		c.recorder.Eventf(replica, corev1.EventTypeWarning, replicator.EventReasonStale,
+			"Replica is outdated, source %s changed at %s", sourceKey(replica),
+			replicator.ContentModified(source).Format(time.RFC3339))
+
+		if requeue, owner := c.owner(replica, source); requeue != nil {
+			if owners[requeue] == nil {
+				owners[requeue] = map[client.ObjectKey]client.Object{}
+			}
+			owners[requeue][client.ObjectKeyFromObject(owner)] = owner
+		}
+	}
+
+	staleReplicas.WithLabelValues(replicator.Kind(c.emptyObjectFn())).Set(float64(stale))
+
+	if c.config.StalenessRequeue {
+		for requeue, objects := range owners {
+			for _, owner := range objects {
+				select {
+				case requeue <- event.GenericEvent{Object: owner}:
+				case <-ctx.Done():
+					return stale, nil
+				}
+			}
+		}
+	}
+
+	return stale, nil
+}
+
+// owner returns the object replicating replica and the channel to requeue it with. Replicas of a policy are written
+// by the policy, not by the reconciliation of their source.
+func (c *Checker[T]) owner(replica client.Object, source T) (chan<- event.GenericEvent, client.Object) {
+	labels := replica.GetLabels()
+	if name, ok := labels[replicator.ClusterPolicyLabel]; ok {
+		return c.requeue.ClusterPolicies, &metav1.PartialObjectMetadata{ObjectMeta: metav1.ObjectMeta{Name: name}}
+	}
+	if name, ok := labels[replicator.PolicyLabel]; ok {
+		// a ReplicationPolicy is located in the namespace of its source
+		return c.requeue.Policies, &metav1.PartialObjectMetadata{ObjectMeta: metav1.ObjectMeta{
+			Namespace: labels[replicator.SourceNamespaceLabel],
+			Name:      name,
+		}}
+	}
+	return c.requeue.Sources, source
+}
+
+// Sources reads and remembers the sources of replicas.
+type Sources[T client.Object] struct {
+	checker *Checker[T]
+	sources map[sourceRead]T
+	missing map[sourceRead]bool
+}
+
+// sourceRead identifies the read of a source, which is either read from the cache or from the API server.
+type sourceRead struct {
+	key     client.ObjectKey
+	fromAPI bool
+}
+
+// NewSources returns Sources reading the sources of the replicas like the Checker.
+func NewSources[T client.Object](c *Checker[T]) *Sources[T] {
+	return &Sources[T]{checker: c, sources: map[sourceRead]T{}, missing: map[sourceRead]bool{}}
+}
+
+// Get returns the source of replica and reports whether it exists. The sources of policy replicas are read from the
+// API server, as the data of sources which are not annotated is not cached.
+func (s *Sources[T]) Get(ctx context.Context, replica client.Object) (T, bool, error) {
+	read := sourceRead{key: sourceKey(replica), fromAPI: replicator.IsPolicyReplica(replica)}
+	if source, ok := s.sources[read]; ok || s.missing[read] {
+		return source, ok, nil
+	}
+
+	var reader client.Reader = s.checker.client
+	if read.fromAPI {
+		reader = s.checker.apiReader
+	}
+
+	source := s.checker.emptyObjectFn()
+	if err := reader.Get(ctx, read.key, source); err != nil {
+		s.missing[read] = true
+		return source, false, client.IgnoreNotFound(err)
+	}
+	s.sources[read] = source
+	return source, true, nil
+}
+
+// sourceKey returns the key of the source of replica.
+func sourceKey(replica client.Object) client.ObjectKey {
+	return client.ObjectKey{
+		Namespace: replica.GetLabels()[replicator.SourceNamespaceLabel],
+		Name:      replica.GetLabels()[replicator.SourceNameLabel],
+	}
+}
+
+// IsStale reports whether replica is outdated for longer than the configured threshold. Replicas no longer targeted
+// by their source are orphans and not stale.
+func (c *Checker[T]) IsStale(ctx context.Context, replica client.Object, source T) (bool, error) {
+	if !IsStale(replica, source, c.config.StalenessThreshold) {
+		return false, nil
+	}
+	if replicator.IsPolicyReplica(replica) {
+		return true, nil
+	}
+
+	orphaned, err := c.orphans.IsOrphaned(ctx, replica, source)
+	return !orphaned, err
+}
+
+// IsStale reports whether replica is outdated for longer than threshold.
+func IsStale(replica, source client.Object, threshold time.Duration) bool {
+	return replicator.IsOutdated(replica, source) && time.Since(replicator.ContentModified(source)) > threshold
+}
@@ -0,0 +1,213 @@
+package staleness
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+
+	"github.com/c0deltin/replik8or/internal/config"
+	"github.com/c0deltin/replik8or/internal/controller/orphan"
+	"github.com/c0deltin/replik8or/internal/replicator"
+)
+
+func TestChecker_check(t *testing.T) {
+	source := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              "source-name",
+			Namespace:         "source-namespace",
+			CreationTimestamp: metav1.NewTime(time.Now().Add(-time.Hour)),
+			Annotations: map[string]string{
+				replicator.ReplicationAllowedAnnotation: "true",
+				replicator.DesiredNamespacesAnnotation:  "up-to-date,outdated",
+			},
+		},
+	}
+	hash, err := replicator.ContentHash(source)
+	require.NoError(t, err)
+
+	upToDate := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "source-name",
+			Namespace: "up-to-date",
+			Labels: map[string]string{
+				replicator.SourceNamespaceLabel: "source-namespace",
+				replicator.SourceNameLabel:      "source-name",
+			},
+			Annotations: map[string]string{replicator.ContentHashAnnotation: hash},
+		},
+	}
+	outdated := upToDate.DeepCopy()
+	outdated.Namespace = "outdated"
+	outdated.Annotations[replicator.ContentHashAnnotation] = "outdated"
+	notTargeted := outdated.DeepCopy()
+	notTargeted.Namespace = "not-targeted"
+	orphaned := outdated.DeepCopy()
+	orphaned.Namespace = "orphan"
+	orphaned.Name = "deleted-source"
+	orphaned.Labels[replicator.SourceNameLabel] = "deleted-source"
+	// the source of the replica is located in a namespace which is not watched
+	unreadable := outdated.DeepCopy()
+	unreadable.Name = "unwatched"
+	unreadable.Labels[replicator.SourceNamespaceLabel] = "unwatched"
+	unreadable.Labels[replicator.SourceNameLabel] = "unwatched"
+
+	fakeClient := fake.NewClientBuilder().
+		WithObjects(source, upToDate, outdated, orphaned, notTargeted, unreadable).
+		WithInterceptorFuncs(interceptor.Funcs{
+			Get: func(ctx context.Context, c client.WithWatch, key client.ObjectKey, obj client.Object,
+				opts ...client.GetOption) error {
+				if key.Namespace == "unwatched" {
+					return errors.New("unknown namespace for the cache")
+				}
+				return c.Get(ctx, key, obj, opts...)
+			},
+		}).
+		Build()
+
+	newChecker := func(
+		cfg *config.Config,
+		recorder record.EventRecorder,
+		requeue chan event.GenericEvent,
+	) *Checker[*corev1.ConfigMap] {
+		return NewChecker[*corev1.ConfigMap](
+			fakeClient,
+			fakeClient,
+			cfg,
+			recorder,
+			replicator.EmptyConfigMap,
+			replicator.EmptyConfigMapList,
+			orphan.NewCollector[*corev1.ConfigMap](
+				fakeClient,
+				cfg,
+				recorder,
+				replicator.EmptyConfigMap,
+				replicator.EmptyConfigMapList,
+			),
+			Requeue{Sources: requeue},
+		)
+	}
+
+	t.Run("stale replicas", func(t *testing.T) {
+		recorder := record.NewFakeRecorder(1)
+		requeue := make(chan event.GenericEvent, 1)
+		c := newChecker(&config.Config{StalenessThreshold: time.Minute, StalenessRequeue: true}, recorder, requeue)
+
+		stale, err := c.check(t.Context())
+
+		assert.NoError(t, err)
+		assert.Equal(t, 1, stale)
+		assert.InDelta(t, 1, testutil.ToFloat64(staleReplicas.WithLabelValues("ConfigMap")), 0)
+		assert.Contains(t, <-recorder.Events, "Warning Stale Replica is outdated")
+		assert.Equal(t, client.ObjectKeyFromObject(source), client.ObjectKeyFromObject((<-requeue).Object))
+	})
+
+	t.Run("within threshold", func(t *testing.T) {
+		c := newChecker(&config.Config{StalenessThreshold: 2 * time.Hour}, &record.FakeRecorder{}, nil)
+
+		stale, err := c.check(t.Context())
+
+		assert.NoError(t, err)
+		assert.Equal(t, 0, stale)
+	})
+}
+
+func TestChecker_check_policyReplicas(t *testing.T) {
+	source := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              "source-name",
+			Namespace:         "source-namespace",
+			CreationTimestamp: metav1.NewTime(time.Now().Add(-time.Hour)),
+		},
+		Data: map[string]string{"foo": "bar"},
+	}
+	hash, err := replicator.ContentHash(source)
+	require.NoError(t, err)
+
+	upToDate := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "source-name",
+			Namespace: "up-to-date",
+			Labels: map[string]string{
+				replicator.SourceNamespaceLabel: "source-namespace",
+				replicator.SourceNameLabel:      "source-name",
+				replicator.PolicyLabel:          "policy",
+			},
+			Annotations: map[string]string{replicator.ContentHashAnnotation: hash},
+		},
+	}
+	outdated := upToDate.DeepCopy()
+	outdated.Namespace = "outdated"
+	outdated.Annotations[replicator.ContentHashAnnotation] = "outdated"
+	delete(outdated.Labels, replicator.PolicyLabel)
+	outdated.Labels[replicator.ClusterPolicyLabel] = "cluster-policy"
+
+	// the source is not annotated, so its data is stripped from the cache
+	stripped := source.DeepCopy()
+	stripped.Data = nil
+	cacheClient := fake.NewClientBuilder().WithObjects(stripped, upToDate, outdated).Build()
+	apiReader := fake.NewClientBuilder().WithObjects(source).Build()
+
+	cfg := &config.Config{StalenessThreshold: time.Minute, StalenessRequeue: true}
+	policies := make(chan event.GenericEvent, 1)
+	clusterPolicies := make(chan event.GenericEvent, 1)
+	c := NewChecker[*corev1.ConfigMap](
+		cacheClient,
+		apiReader,
+		cfg,
+		record.NewFakeRecorder(1),
+		replicator.EmptyConfigMap,
+		replicator.EmptyConfigMapList,
+		orphan.NewCollector[*corev1.ConfigMap](
+			cacheClient,
+			cfg,
+			&record.FakeRecorder{},
+			replicator.EmptyConfigMap,
+			replicator.EmptyConfigMapList,
+		),
+		Requeue{Policies: policies, ClusterPolicies: clusterPolicies},
+	)
+
+	stale, err := c.check(t.Context())
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, stale)
+	// the policy replicating the stale replica is requeued, not its source
+	assert.Equal(t, client.ObjectKey{Name: "cluster-policy"}, client.ObjectKeyFromObject((<-clusterPolicies).Object))
+	assert.Empty(t, policies)
+}
+
+func TestIsStale(t *testing.T) {
+	source := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			CreationTimestamp: metav1.NewTime(time.Now().Add(-time.Hour)),
+			// the status written by the operator just now does not change the content of the source
+			ManagedFields: []metav1.ManagedFieldsEntry{{
+				Manager:  "replik8or",
+				Time:     ptr.To(metav1.Now()),
+				FieldsV1: &metav1.FieldsV1{Raw: []byte(`{"f:metadata":{"f:annotations":{}}}`)},
+			}},
+		},
+		Data: map[string]string{"foo": "bar"},
+	}
+	replica := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Annotations: map[string]string{replicator.ContentHashAnnotation: "outdated"},
+		},
+	}
+
+	assert.True(t, IsStale(replica, source, time.Minute))
+	assert.False(t, IsStale(replica, source, 2*time.Hour))
+}
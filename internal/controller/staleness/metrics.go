@@ -0,0 +1,15 @@
+package staleness
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+var staleReplicas = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "replik8or_stale_replicas",
+	Help: "Number of replicas not updated after their source changed.",
+}, []string{"kind"})
+
+func init() {
+	metrics.Registry.MustRegister(staleReplicas)
+}
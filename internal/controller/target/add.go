@@ -0,0 +1,113 @@
+package target
+
+import (
+	"context"
+	"strings"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/api/meta"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/c0deltin/replik8or/internal/controller"
+	"github.com/c0deltin/replik8or/internal/replicator"
+)
+
+const replicateFromIndexField = "replicate.from"
+
+func (r *Reconciler[T]) SetupWithManager(name string, mgr manager.Manager) error {
+	if err := r.setReplicateFromIndexer(mgr); err != nil {
+		return err
+	}
+
+	return builder.ControllerManagedBy(mgr).
+		Named(name).
+		For(r.emptyObjectFn(), builder.WithPredicates(r.targetPredicates())).
+		Watches(
+			r.emptyObjectFn(),
+			handler.EnqueueRequestsFromMapFunc(r.mapSourcesToTargets),
+			builder.WithPredicates(r.sourcePredicates()),
+		).
+		WithOptions(controller.Options(r.config)).
+		WithLogConstructor(func(r *reconcile.Request) logr.Logger {
+			return ctrl.Log.WithName("replik8or")
+		}).
+		Complete(r)
+}
+
+func (r *Reconciler[T]) setReplicateFromIndexer(mgr manager.Manager) error {
+	return mgr.GetFieldIndexer().IndexField(
+		context.Background(),
+		r.emptyObjectFn(),
+		replicateFromIndexField,
+		func(object client.Object) []string {
+			if v, ok := object.GetAnnotations()[replicator.ReplicateFromAnnotation]; ok {
+				return []string{strings.TrimSpace(v)}
+			}
+			return nil
+		},
+	)
+}
+
+func (r *Reconciler[T]) targetPredicates() predicate.Funcs {
+	return predicate.Funcs{
+		CreateFunc: func(e event.CreateEvent) bool {
+			return replicator.HasAnnotations(e.Object, replicator.ReplicateFromAnnotation)
+		},
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			return replicator.HasAnnotations(e.ObjectNew, replicator.ReplicateFromAnnotation)
+		},
+		DeleteFunc: func(e event.DeleteEvent) bool {
+			return false
+		},
+		GenericFunc: func(e event.GenericEvent) bool {
+			return false
+		},
+	}
+}
+
+func (r *Reconciler[T]) sourcePredicates() predicate.Funcs {
+	return predicate.Funcs{
+		CreateFunc: func(e event.CreateEvent) bool {
+			return replicator.HasAnnotations(e.Object, replicator.ReplicationAllowedNamespacesAnnotation)
+		},
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			// revoking the permission is reported on the targets as well
+			return replicator.HasAnnotations(e.ObjectOld, replicator.ReplicationAllowedNamespacesAnnotation) ||
+				replicator.HasAnnotations(e.ObjectNew, replicator.ReplicationAllowedNamespacesAnnotation)
+		},
+		DeleteFunc: func(e event.DeleteEvent) bool {
+			return false
+		},
+		GenericFunc: func(e event.GenericEvent) bool {
+			return false
+		},
+	}
+}
+
+// mapSourcesToTargets enqueues all objects replicating from the source.
+func (r *Reconciler[T]) mapSourcesToTargets(ctx context.Context, source client.Object) []reconcile.Request {
+	var targetList = r.emptyObjectListFn()
+	if err := r.client.List(ctx, targetList, client.MatchingFields{
+		replicateFromIndexField: client.ObjectKeyFromObject(source).String(),
+	}); err != nil {
+		return nil
+	}
+
+	targets, err := meta.ExtractList(targetList)
+	if err != nil {
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for _, target := range targets {
+		requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(target.(client.Object))})
+	}
+	return requests
+}
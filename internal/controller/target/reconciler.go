@@ -0,0 +1,110 @@
+package target
+
+import (
+	"context"
+	"slices"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/c0deltin/replik8or/internal/config"
+	"github.com/c0deltin/replik8or/internal/replicator"
+	"github.com/c0deltin/replik8or/internal/tracing"
+)
+
+var tracer = otel.Tracer("github.com/c0deltin/replik8or/internal/controller/target")
+
+// Reconciler fills the data of objects annotated with replicator.ReplicateFromAnnotation from the referenced source.
+type Reconciler[T client.Object] struct {
+	kind     string
+	client   client.Client
+	config   *config.Config
+	recorder record.EventRecorder
+
+	emptyObjectFn     func() T
+	emptyObjectListFn func() client.ObjectList
+
+	replicator *replicator.Replicator[T]
+}
+
+func NewReconciler[T client.Object](
+	client client.Client,
+	config *config.Config,
+	recorder record.EventRecorder,
+	emptyObjectFn func() T,
+	emptyObjectListFn func() client.ObjectList,
+) *Reconciler[T] {
+	return &Reconciler[T]{
+		kind:              replicator.Kind(emptyObjectFn()),
+		client:            client,
+		config:            config,
+		recorder:          recorder,
+		emptyObjectFn:     emptyObjectFn,
+		emptyObjectListFn: emptyObjectListFn,
+		replicator:        replicator.New[T](client, client, config, recorder),
+	}
+}
+
+func (r *Reconciler[T]) Reconcile(ctx context.Context, req reconcile.Request) (result reconcile.Result, err error) {
+	ctx, span := tracer.Start(ctx, "Reconciler.Reconcile", trace.WithAttributes(
+		tracing.TargetNamespaceKey.String(req.Namespace),
+		tracing.KindKey.String(r.kind),
+	))
+	defer func() { tracing.End(span, err) }()
+
+	var target = r.emptyObjectFn()
+	if err := r.client.Get(ctx, req.NamespacedName, target); err != nil {
+		return reconcile.Result{}, client.IgnoreNotFound(err)
+	}
+	if !target.GetDeletionTimestamp().IsZero() ||
+		!replicator.HasAnnotations(target, replicator.ReplicateFromAnnotation) {
+		return reconcile.Result{}, nil
+	}
+
+	sourceKey, err := replicator.ParseReplicateFrom(target.GetAnnotations()[replicator.ReplicateFromAnnotation])
+	if err != nil {
+		r.recorder.Eventf(target, corev1.EventTypeWarning, replicator.EventReasonReplicationFailed,
+			"Invalid %s annotation: %v", replicator.ReplicateFromAnnotation, err)
+		return reconcile.Result{}, nil
+	}
+	span.SetAttributes(tracing.SourceKey.String(sourceKey.String()))
+
+	if sourceKey == client.ObjectKeyFromObject(target) {
+		r.recorder.Event(target, corev1.EventTypeWarning, replicator.EventReasonReplicationFailed,
+			"Object cannot replicate from itself")
+		return reconcile.Result{}, nil
+	}
+	if slices.Contains(r.config.DisallowedNamespaces, target.GetNamespace()) {
+		r.recorder.Eventf(target, corev1.EventTypeWarning, replicator.EventReasonPullDenied,
+			"Replicating into namespace %s is disallowed", target.GetNamespace())
+		return reconcile.Result{}, nil
+	}
+	if slices.Contains(r.config.DisallowedSourceNamespaces, sourceKey.Namespace) {
+		r.recorder.Eventf(target, corev1.EventTypeWarning, replicator.EventReasonPullDenied,
+			"Replicating from namespace %s is disallowed", sourceKey.Namespace)
+		return reconcile.Result{}, nil
+	}
+
+	// the source is watched, so the target is reconciled again once the source exists or allows the pull
+	var source = r.emptyObjectFn()
+	if err := r.client.Get(ctx, sourceKey, source); err != nil {
+		if apierrors.IsNotFound(err) {
+			r.recorder.Eventf(target, corev1.EventTypeWarning, replicator.EventReasonReplicationFailed,
+				"Source %s not found", sourceKey)
+			return reconcile.Result{}, nil
+		}
+		return reconcile.Result{}, err
+	}
+	if !replicator.AllowsPullFrom(source, target.GetNamespace()) {
+		r.recorder.Eventf(target, corev1.EventTypeWarning, replicator.EventReasonPullDenied,
+			"Source %s does not allow replication to namespace %s", sourceKey, target.GetNamespace())
+		return reconcile.Result{}, nil
+	}
+
+	return reconcile.Result{}, r.replicator.Pull(ctx, source, target)
+}
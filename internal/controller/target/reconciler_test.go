@@ -0,0 +1,113 @@
+package target
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/c0deltin/replik8or/internal/config"
+	"github.com/c0deltin/replik8or/internal/replicator"
+)
+
+func TestReconciler_Reconcile(t *testing.T) {
+	source := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "ca-bundle",
+			Namespace: "infra",
+			Annotations: map[string]string{
+				replicator.ReplicationAllowedNamespacesAnnotation: "team-*, ci",
+			},
+		},
+		Data: map[string]string{"ca.crt": "certificate"},
+	}
+	newTarget := func(namespace, replicateFrom string) *corev1.ConfigMap {
+		return &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "ca",
+				Namespace:   namespace,
+				Labels:      map[string]string{"app": "foo"},
+				Annotations: map[string]string{replicator.ReplicateFromAnnotation: replicateFrom},
+			},
+		}
+	}
+
+	tests := []struct {
+		name         string
+		target       *corev1.ConfigMap
+		config       *config.Config
+		expectedData map[string]string
+		expectEvent  string
+	}{
+		{
+			name:         "pull data from source",
+			target:       newTarget("team-a", "infra/ca-bundle"),
+			config:       &config.Config{},
+			expectedData: source.Data,
+		},
+		{
+			name:        "namespace not allowed by source",
+			target:      newTarget("other", "infra/ca-bundle"),
+			config:      &config.Config{},
+			expectEvent: "Warning PullDenied Source infra/ca-bundle does not allow replication to namespace other",
+		},
+		{
+			name:        "namespace disallowed by configuration",
+			target:      newTarget("ci", "infra/ca-bundle"),
+			config:      &config.Config{DisallowedNamespaces: []string{"ci"}},
+			expectEvent: "Warning PullDenied Replicating into namespace ci is disallowed",
+		},
+		{
+			name:        "source namespace disallowed by configuration",
+			target:      newTarget("ci", "infra/ca-bundle"),
+			config:      &config.Config{DisallowedSourceNamespaces: []string{"infra"}},
+			expectEvent: "Warning PullDenied Replicating from namespace infra is disallowed",
+		},
+		{
+			name:        "source not found",
+			target:      newTarget("ci", "infra/missing"),
+			config:      &config.Config{},
+			expectEvent: "Warning ReplicationFailed Source infra/missing not found",
+		},
+		{
+			name:   "invalid reference",
+			target: newTarget("ci", "ca-bundle"),
+			config: &config.Config{},
+			expectEvent: "Warning ReplicationFailed Invalid replik8or.c0deltin.dev/replicate-from annotation: " +
+				"expected <namespace>/<name>, got \"ca-bundle\"",
+		},
+		{
+			name:         "dry-run",
+			target:       newTarget("ci", "infra/ca-bundle"),
+			config:       &config.Config{DryRun: true},
+			expectedData: nil,
+			expectEvent:  "Normal DryRun Would update replica ci/ca",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fakeClient := fake.NewFakeClient(source.DeepCopy(), tt.target)
+			recorder := record.NewFakeRecorder(1)
+			r := NewReconciler[*corev1.ConfigMap](fakeClient, tt.config, recorder,
+				replicator.EmptyConfigMap, replicator.EmptyConfigMapList)
+
+			_, err := r.Reconcile(t.Context(), reconcile.Request{NamespacedName: client.ObjectKeyFromObject(tt.target)})
+			assert.NoError(t, err)
+
+			var actual corev1.ConfigMap
+			err = fakeClient.Get(t.Context(), client.ObjectKeyFromObject(tt.target), &actual)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedData, actual.Data)
+			assert.Equal(t, tt.target.Labels, actual.Labels)
+
+			if tt.expectEvent != "" {
+				assert.Equal(t, tt.expectEvent, <-recorder.Events)
+			}
+		})
+	}
+}
@@ -0,0 +1,173 @@
+// Package namespaces keeps the namespaces of the cluster in memory, so targeting replicas does not require any
+// requests.
+package namespaces
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	toolscache "k8s.io/client-go/tools/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+// ErrNotSynced is returned while the index does not contain all namespaces yet.
+var ErrNotSynced = errors.New("namespace index not synced")
+
+// Namespace is the indexed part of a namespace.
+type Namespace struct {
+	Name        string
+	Phase       corev1.NamespacePhase
+	Labels      map[string]string
+	Annotations map[string]string
+}
+
+// Index is an in-memory index of namespaces by name. It is safe for concurrent use.
+type Index struct {
+	mu         sync.RWMutex
+	namespaces map[string]Namespace
+	hasSynced  func() bool
+}
+
+// NewIndex returns an Index containing the given namespaces. Namespaces of the cluster are added by SetupWithManager.
+func NewIndex(namespaces ...Namespace) *Index {
+	i := &Index{namespaces: make(map[string]Namespace, len(namespaces))}
+	for _, namespace := range namespaces {
+		i.Set(namespace)
+	}
+	return i
+}
+
+// NewStaticIndex returns an Index containing the active namespaces with the given names. The index is not meant to
+// be set up with a manager, it is used when the operator is restricted to a fixed set of namespaces. Without permission
+// to read namespaces, they are assumed to exist and to be active; replicas into missing namespaces are skipped.
+func NewStaticIndex(names ...string) *Index {
+	i := NewIndex()
+	for _, name := range names {
+		i.Set(Namespace{Name: name})
+	}
+	return i
+}
+
+// Metadata returns the object to watch namespaces by their metadata only.
+func Metadata() *metav1.PartialObjectMetadata {
+	var namespace metav1.PartialObjectMetadata
+	namespace.SetGroupVersionKind(corev1.SchemeGroupVersion.WithKind("Namespace"))
+	return &namespace
+}
+
+// SetupWithManager maintains the index from the namespace informer of the manager.
+func (i *Index) SetupWithManager(ctx context.Context, mgr manager.Manager) error {
+	informer, err := mgr.GetCache().GetInformer(ctx, Metadata())
+	if err != nil {
+		return err
+	}
+
+	registration, err := informer.AddEventHandler(toolscache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj any) {
+			if object, ok := obj.(client.Object); ok {
+				i.Set(FromObject(object))
+			}
+		},
+		UpdateFunc: func(_, obj any) {
+			if object, ok := obj.(client.Object); ok {
+				i.Set(FromObject(object))
+			}
+		},
+		DeleteFunc: func(obj any) {
+			if tombstone, ok := obj.(toolscache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			if object, ok := obj.(client.Object); ok {
+				i.Delete(object.GetName())
+			}
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.hasSynced = registration.HasSynced
+	return nil
+}
+
+// HasSynced reports whether the index contains all namespaces of the informer.
+func (i *Index) HasSynced() bool {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	return i.hasSynced == nil || i.hasSynced()
+}
+
+// Set adds or replaces namespace. Namespaces without phase are active.
+func (i *Index) Set(namespace Namespace) {
+	if namespace.Phase == "" {
+		namespace.Phase = corev1.NamespaceActive
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.namespaces[namespace.Name] = namespace
+}
+
+// Delete removes the namespace with the given name.
+func (i *Index) Delete(name string) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	delete(i.namespaces, name)
+}
+
+// Get returns the namespace with the given name and reports whether it exists.
+func (i *Index) Get(name string) (Namespace, bool, error) {
+	if !i.HasSynced() {
+		return Namespace{}, false, ErrNotSynced
+	}
+
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	namespace, ok := i.namespaces[name]
+	return namespace, ok, nil
+}
+
+// List returns the namespaces matching selector sorted by name.
+func (i *Index) List(selector labels.Selector) ([]Namespace, error) {
+	if !i.HasSynced() {
+		return nil, ErrNotSynced
+	}
+
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	var namespaces []Namespace
+	for _, namespace := range i.namespaces {
+		if selector.Matches(labels.Set(namespace.Labels)) {
+			namespaces = append(namespaces, namespace)
+		}
+	}
+	slices.SortFunc(namespaces, func(a, b Namespace) int { return strings.Compare(a.Name, b.Name) })
+	return namespaces, nil
+}
+
+// FromObject returns the indexed part of a namespace. Namespaces watched by their metadata only are terminating as
+// soon as their deletion timestamp is set.
+func FromObject(object client.Object) Namespace {
+	phase := corev1.NamespaceActive
+	if namespace, ok := object.(*corev1.Namespace); ok && namespace.Status.Phase != "" {
+		phase = namespace.Status.Phase
+	} else if !object.GetDeletionTimestamp().IsZero() {
+		phase = corev1.NamespaceTerminating
+	}
+
+	return Namespace{
+		Name:        object.GetName(),
+		Phase:       phase,
+		Labels:      object.GetLabels(),
+		Annotations: object.GetAnnotations(),
+	}
+}
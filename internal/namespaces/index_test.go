@@ -0,0 +1,90 @@
+package namespaces
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+func TestIndex(t *testing.T) {
+	index := NewIndex(
+		Namespace{Name: "foo", Labels: map[string]string{"tier": "app"}},
+		Namespace{Name: "bar"},
+	)
+	index.Set(Namespace{Name: "baz", Labels: map[string]string{"tier": "app"}})
+
+	namespace, ok, err := index.Get("foo")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "app", namespace.Labels["tier"])
+	assert.Equal(t, corev1.NamespaceActive, namespace.Phase)
+
+	all, err := index.List(labels.Everything())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"bar", "baz", "foo"}, names(all))
+
+	selected, err := index.List(labels.SelectorFromSet(labels.Set{"tier": "app"}))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"baz", "foo"}, names(selected))
+
+	index.Delete("foo")
+	_, ok, err = index.Get("foo")
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestNewStaticIndex(t *testing.T) {
+	index := NewStaticIndex("foo", "bar")
+	assert.True(t, index.HasSynced())
+
+	all, err := index.List(labels.Everything())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"bar", "foo"}, names(all))
+	for _, namespace := range all {
+		assert.Equal(t, corev1.NamespaceActive, namespace.Phase)
+	}
+}
+
+func TestIndex_notSynced(t *testing.T) {
+	index := NewIndex()
+	index.hasSynced = func() bool { return false }
+
+	_, _, err := index.Get("foo")
+	assert.ErrorIs(t, err, ErrNotSynced)
+	_, err = index.List(labels.Everything())
+	assert.ErrorIs(t, err, ErrNotSynced)
+}
+
+func TestFromObject(t *testing.T) {
+	now := metav1.Now()
+	active := &metav1.PartialObjectMetadata{ObjectMeta: metav1.ObjectMeta{
+		Name:   "active",
+		Labels: map[string]string{"tier": "app"},
+	}}
+	terminating := &metav1.PartialObjectMetadata{ObjectMeta: metav1.ObjectMeta{
+		Name:              "terminating",
+		DeletionTimestamp: &now,
+	}}
+
+	assert.Equal(t, Namespace{
+		Name:   "active",
+		Phase:  corev1.NamespaceActive,
+		Labels: map[string]string{"tier": "app"},
+	}, FromObject(active))
+	assert.Equal(t, corev1.NamespaceTerminating, FromObject(terminating).Phase)
+	assert.Equal(t, corev1.NamespaceTerminating, FromObject(&corev1.Namespace{
+		Status: corev1.NamespaceStatus{Phase: corev1.NamespaceTerminating},
+	}).Phase)
+}
+
+func names(namespaces []Namespace) []string {
+	var names []string
+	for _, namespace := range namespaces {
+		names = append(names, namespace.Name)
+	}
+	return names
+}
@@ -0,0 +1,122 @@
+package replicator
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// defaultServiceAccount is used to authorize the replication of sources and policies without RunAsAnnotation.
+const defaultServiceAccount = "default"
+
+// authorizedVerbs are the verbs the ServiceAccount of a source requires on replicas within a target namespace.
+var authorizedVerbs = []string{"create", "update"}
+
+const (
+	// reviewTTL is the duration the outcome of a SubjectAccessReview is reused, so the target namespaces are not
+	// reviewed again on every reconciliation. Changed permissions take effect once the outcome expired.
+	reviewTTL = 30 * time.Second
+	// maxReviews is the maximum number of cached SubjectAccessReview outcomes.
+	maxReviews = 10000
+)
+
+// reviewKey identifies the outcome of a SubjectAccessReview.
+type reviewKey struct {
+	user, namespace, verb, resource string
+}
+
+// AuthorizedNamespaces filters the namespaces to the ones the ServiceAccount of owner may write replicas to. The owner
+// is the source itself or the namespaced policy replicating it. The ServiceAccount is taken from the RunAsAnnotation of
+// owner and always lives in the namespace of owner, so replicating grants no more permissions than the owner already
+// has. Unauthorized namespaces are reported using an Event on owner.
+func (r *Replicator[T]) AuthorizedNamespaces(
+	ctx context.Context,
+	owner client.Object,
+	namespaces []string,
+) ([]string, error) {
+	serviceAccount := owner.GetAnnotations()[RunAsAnnotation]
+	if serviceAccount == "" {
+		serviceAccount = defaultServiceAccount
+	}
+	subject := subjectAccessReviewSpec(owner.GetNamespace(), serviceAccount)
+	resource := strings.ToLower(Kind(*new(T))) + "s"
+
+	// a cluster-wide permission covers all namespaces
+	allowed, err := r.allowed(ctx, subject, "", resource)
+	if err != nil {
+		return nil, err
+	}
+	if allowed {
+		return namespaces, nil
+	}
+
+	var authorized, denied []string
+	for _, namespace := range namespaces {
+		allowed, err := r.allowed(ctx, subject, namespace, resource)
+		if err != nil {
+			return nil, err
+		}
+		if !allowed {
+			denied = append(denied, namespace)
+			continue
+		}
+		authorized = append(authorized, namespace)
+	}
+
+	if len(denied) > 0 {
+		r.recorder.Eventf(owner, corev1.EventTypeWarning, EventReasonUnauthorized,
+			"ServiceAccount %s/%s may not write %s to namespaces %s", owner.GetNamespace(), serviceAccount,
+			resource, strings.Join(denied, ","))
+	}
+	return authorized, nil
+}
+
+// allowed reports whether subject may write resource within namespace. The outcome of each review is cached for
+// reviewTTL.
+func (r *Replicator[T]) allowed(
+	ctx context.Context,
+	subject authorizationv1.SubjectAccessReviewSpec,
+	namespace, resource string,
+) (bool, error) {
+	for _, verb := range authorizedVerbs {
+		key := reviewKey{user: subject.User, namespace: namespace, verb: verb, resource: resource}
+		if allowed, ok := r.reviews.Get(key); ok {
+			if !allowed.(bool) {
+				return false, nil
+			}
+			continue
+		}
+
+		review := &authorizationv1.SubjectAccessReview{Spec: *subject.DeepCopy()}
+		review.Spec.ResourceAttributes = &authorizationv1.ResourceAttributes{
+			Namespace: namespace,
+			Verb:      verb,
+			Resource:  resource,
+		}
+		if err := r.authClient.Create(ctx, review); err != nil {
+			return false, fmt.Errorf("reviewing access to %s in namespace %q: %w", resource, namespace, err)
+		}
+		r.reviews.Add(key, review.Status.Allowed, reviewTTL)
+		if !review.Status.Allowed {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// subjectAccessReviewSpec returns the user and groups of the ServiceAccount as authenticated by the API server.
+func subjectAccessReviewSpec(namespace, serviceAccount string) authorizationv1.SubjectAccessReviewSpec {
+	return authorizationv1.SubjectAccessReviewSpec{
+		User: fmt.Sprintf("system:serviceaccount:%s:%s", namespace, serviceAccount),
+		Groups: []string{
+			"system:serviceaccounts",
+			"system:serviceaccounts:" + namespace,
+			"system:authenticated",
+		},
+	}
+}
@@ -0,0 +1,58 @@
+package replicator
+
+import (
+	"slices"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// managedPrefixes are the prefixes of the labels, annotations and finalizers replik8or sets or reacts to.
+var managedPrefixes = []string{"replik8or.c0deltin.dev/", "replicator.c0deltin.dev/"}
+
+// dataAnnotations mark the objects whose data is read from the cache: sources replicated by annotation, sources
+// allowing a pull and pull targets.
+var dataAnnotations = []string{
+	ReplicationAllowedAnnotation,
+	ReplicationAllowedNamespacesAnnotation,
+	ReplicateFromAnnotation,
+}
+
+// StripUnreadData is a cache transform removing the data of ConfigMaps and Secrets which is never read from the
+// cache, so that only the data of sources and pull targets is held in memory. Replicas are compared by their
+// ContentHashAnnotation and read from the API server before they are written. Annotations can not be selected by the
+// API server, which is why the objects are filtered after they were received.
+//
+// Objects read from a cache using this transform must not be written back unless their data is kept.
+func StripUnreadData(obj any) (any, error) {
+	object, ok := obj.(client.Object)
+	if !ok || isDataRead(object) {
+		return obj, nil
+	}
+
+	switch o := object.(type) {
+	case *corev1.ConfigMap:
+		o.Data = nil
+		o.BinaryData = nil
+	case *corev1.Secret:
+		o.Data = nil
+		o.StringData = nil
+	}
+	return object, nil
+}
+
+// isDataRead reports whether the data of object is read from the cache. Sources keep their data as long as they
+// carry a finalizer of replik8or, even if their annotations were already removed.
+func isDataRead(object client.Object) bool {
+	for _, annotation := range dataAnnotations {
+		if _, ok := object.GetAnnotations()[annotation]; ok {
+			return true
+		}
+	}
+	return slices.ContainsFunc(object.GetFinalizers(), func(finalizer string) bool {
+		return slices.ContainsFunc(managedPrefixes, func(prefix string) bool {
+			return strings.HasPrefix(finalizer, prefix)
+		})
+	})
+}
@@ -0,0 +1,59 @@
+package replicator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestStripUnreadData(t *testing.T) {
+	tests := []struct {
+		name     string
+		meta     metav1.ObjectMeta
+		stripped bool
+	}{
+		{name: "unmanaged", meta: metav1.ObjectMeta{Annotations: map[string]string{"foo": "bar"}}, stripped: true},
+		{
+			name: "source",
+			meta: metav1.ObjectMeta{Annotations: map[string]string{ReplicationAllowedAnnotation: "true"}},
+		},
+		{
+			name: "pull source",
+			meta: metav1.ObjectMeta{Annotations: map[string]string{ReplicationAllowedNamespacesAnnotation: "*"}},
+		},
+		{
+			name: "pull target",
+			meta: metav1.ObjectMeta{Annotations: map[string]string{ReplicateFromAnnotation: "foo/bar"}},
+		},
+		{
+			name: "replica",
+			meta: metav1.ObjectMeta{
+				Labels:      map[string]string{SourceNameLabel: "foo"},
+				Annotations: map[string]string{ContentHashAnnotation: "hash"},
+			},
+			stripped: true,
+		},
+		{name: "finalized source", meta: metav1.ObjectMeta{Finalizers: []string{"replik8or.c0deltin.dev/source"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configMap := &corev1.ConfigMap{
+				ObjectMeta: tt.meta,
+				Data:       map[string]string{"foo": "bar"},
+				BinaryData: map[string][]byte{"bar": []byte("baz")},
+			}
+			obj, err := StripUnreadData(configMap)
+			require.NoError(t, err)
+			assert.Equal(t, tt.stripped, obj.(*corev1.ConfigMap).Data == nil)
+			assert.Equal(t, tt.stripped, obj.(*corev1.ConfigMap).BinaryData == nil)
+
+			secret := &corev1.Secret{ObjectMeta: tt.meta, Data: map[string][]byte{"foo": []byte("bar")}}
+			obj, err = StripUnreadData(secret)
+			require.NoError(t, err)
+			assert.Equal(t, tt.stripped, obj.(*corev1.Secret).Data == nil)
+		})
+	}
+}
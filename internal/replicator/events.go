@@ -0,0 +1,17 @@
+package replicator
+
+// Reasons of the events recorded on sources and replicas.
+const (
+	EventReasonCreated           = "Created"
+	EventReasonUpdated           = "Updated"
+	EventReasonDeleted           = "Deleted"
+	EventReasonDryRun            = "DryRun"
+	EventReasonReplicated        = "Replicated"
+	EventReasonReplicationFailed = "ReplicationFailed"
+	EventReasonConflict          = "Conflict"
+	EventReasonOverridden        = "Overridden"
+	EventReasonPullDenied        = "PullDenied"
+	EventReasonUnauthorized      = "Unauthorized"
+	EventReasonStale             = "Stale"
+	EventReasonOrphaned          = "Orphaned"
+)
@@ -0,0 +1,51 @@
+package replicator
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// payload is the part of a source which is replicated.
+type payload struct {
+	Type        corev1.SecretType `json:"type,omitempty"`
+	Immutable   *bool             `json:"immutable,omitempty"`
+	Data        any               `json:"data,omitempty"`
+	BinaryData  map[string][]byte `json:"binaryData,omitempty"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// ContentHash returns a stable hash of the payload replicated from source: its data, type and the labels and
+// annotations copied to replicas. Unlike the resourceVersion, the hash does not change with metadata only relevant
+// to the source, e.g. its finalizers or replication status.
+func ContentHash(source client.Object) (string, error) {
+	p := payload{
+		Labels:      source.GetLabels(),
+		Annotations: replicatedAnnotations(source),
+	}
+	switch v := source.(type) {
+	case *corev1.Secret:
+		p.Type = v.Type
+		p.Immutable = v.Immutable
+		p.Data = v.Data
+	case *corev1.ConfigMap:
+		p.Immutable = v.Immutable
+		p.Data = v.Data
+		p.BinaryData = v.BinaryData
+	default:
+		return "", fmt.Errorf("type %T not implemented", v)
+	}
+
+	// maps are encoded with sorted keys, which makes the encoding stable
+	encoded, err := json.Marshal(p)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(encoded)
+	return hex.EncodeToString(sum[:]), nil
+}
@@ -0,0 +1,60 @@
+package replicator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestContentHash(t *testing.T) {
+	source := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "source-name",
+			Namespace:       "source-namespace",
+			ResourceVersion: "1",
+			Labels:          map[string]string{"app": "foo"},
+			Annotations:     map[string]string{ReplicationAllowedAnnotation: "true"},
+		},
+		Type: corev1.SecretTypeOpaque,
+		Data: map[string][]byte{"foo": []byte("bar"), "bar": []byte("baz")},
+	}
+	hash, err := ContentHash(source)
+	require.NoError(t, err)
+
+	t.Run("stable for source metadata", func(t *testing.T) {
+		changed := source.DeepCopy()
+		changed.ResourceVersion = "2"
+		changed.Finalizers = []string{"replik8or.c0deltin.dev/source"}
+		changed.Annotations[ReplicatedToAnnotation] = "foo,bar"
+		changed.Annotations[DesiredNamespacesAnnotation] = "foo,bar"
+
+		changedHash, err := ContentHash(changed)
+		require.NoError(t, err)
+		assert.Equal(t, hash, changedHash)
+	})
+
+	tests := map[string]func(*corev1.Secret){
+		"data":       func(s *corev1.Secret) { s.Data["foo"] = []byte("baz") },
+		"type":       func(s *corev1.Secret) { s.Type = corev1.SecretTypeDockercfg },
+		"label":      func(s *corev1.Secret) { s.Labels["app"] = "bar" },
+		"annotation": func(s *corev1.Secret) { s.Annotations["custom-annotation"] = "bar" },
+	}
+	for name, change := range tests {
+		t.Run("changes with "+name, func(t *testing.T) {
+			changed := source.DeepCopy()
+			change(changed)
+
+			changedHash, err := ContentHash(changed)
+			require.NoError(t, err)
+			assert.NotEqual(t, hash, changedHash)
+		})
+	}
+
+	t.Run("unknown type", func(t *testing.T) {
+		_, err := ContentHash(&corev1.Namespace{})
+		assert.Error(t, err)
+	})
+}
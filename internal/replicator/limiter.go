@@ -0,0 +1,103 @@
+package replicator
+
+import (
+	"context"
+	"sync"
+
+	"golang.org/x/time/rate"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// writeLimiter limits the replica writes per source, so a source fanning out to many namespaces does not
+// monopolize the API server.
+type writeLimiter struct {
+	mu       sync.Mutex
+	limit    rate.Limit
+	burst    int
+	limiters map[client.ObjectKey]*rate.Limiter
+}
+
+// newWriteLimiter returns a writeLimiter allowing qps writes per second and source. It returns nil if qps is
+// not positive, which disables the limit.
+func newWriteLimiter(qps float64) *writeLimiter {
+	if qps <= 0 {
+		return nil
+	}
+	return &writeLimiter{
+		limit:    rate.Limit(qps),
+		burst:    max(1, int(qps)),
+		limiters: map[client.ObjectKey]*rate.Limiter{},
+	}
+}
+
+// wait blocks until a write of a replica of source is allowed or ctx is done.
+func (l *writeLimiter) wait(ctx context.Context, source client.Object) error {
+	if l == nil {
+		return nil
+	}
+
+	l.mu.Lock()
+	limiter, ok := l.limiters[NamespacedName(source)]
+	if !ok {
+		limiter = rate.NewLimiter(l.limit, l.burst)
+		l.limiters[NamespacedName(source)] = limiter
+	}
+	l.mu.Unlock()
+
+	return limiter.Wait(ctx)
+}
+
+// forget drops the limiter of source.
+func (l *writeLimiter) forget(source client.ObjectKey) {
+	if l == nil {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.limiters, source)
+}
+
+// limitedClient waits for the writeLimiter of source before each write. Reads are never limited, so replicas of an
+// unchanged source are compared without being throttled.
+type limitedClient struct {
+	client.Client
+	limiter *writeLimiter
+	source  client.Object
+}
+
+// client returns c limited by the writeLimiter of source, or c itself if the limit is disabled.
+func (l *writeLimiter) client(c client.Client, source client.Object) client.Client {
+	if l == nil {
+		return c
+	}
+	return &limitedClient{Client: c, limiter: l, source: source}
+}
+
+func (c *limitedClient) Create(ctx context.Context, obj client.Object, opts ...client.CreateOption) error {
+	if err := c.limiter.wait(ctx, c.source); err != nil {
+		return err
+	}
+	return c.Client.Create(ctx, obj, opts...)
+}
+
+func (c *limitedClient) Update(ctx context.Context, obj client.Object, opts ...client.UpdateOption) error {
+	if err := c.limiter.wait(ctx, c.source); err != nil {
+		return err
+	}
+	return c.Client.Update(ctx, obj, opts...)
+}
+
+func (c *limitedClient) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	if err := c.limiter.wait(ctx, c.source); err != nil {
+		return err
+	}
+	return c.Client.Patch(ctx, obj, patch, opts...)
+}
+
+func (c *limitedClient) Delete(ctx context.Context, obj client.Object, opts ...client.DeleteOption) error {
+	if err := c.limiter.wait(ctx, c.source); err != nil {
+		return err
+	}
+	return c.Client.Delete(ctx, obj, opts...)
+}
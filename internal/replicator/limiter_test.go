@@ -0,0 +1,55 @@
+package replicator
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestWriteLimiter(t *testing.T) {
+	source := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "source-name", Namespace: "source-namespace"}}
+	other := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "source-namespace"}}
+
+	t.Run("disabled", func(t *testing.T) {
+		l := newWriteLimiter(0)
+		assert.Nil(t, l)
+		assert.NoError(t, l.wait(t.Context(), source))
+		l.forget(NamespacedName(source))
+	})
+
+	t.Run("limit per source", func(t *testing.T) {
+		l := newWriteLimiter(1)
+		assert.NoError(t, l.wait(t.Context(), source))
+		// the limit of one source does not affect the others
+		assert.NoError(t, l.wait(t.Context(), other))
+
+		ctx, cancel := context.WithTimeout(t.Context(), 10*time.Millisecond)
+		defer cancel()
+		assert.Error(t, l.wait(ctx, source))
+
+		l.forget(NamespacedName(source))
+		assert.NoError(t, l.wait(ctx, source))
+	})
+}
+
+func TestWriteLimiter_client(t *testing.T) {
+	source := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "source-name", Namespace: "source-namespace"}}
+	replica := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "source-name", Namespace: "testing"}}
+
+	l := newWriteLimiter(1)
+	c := l.client(fake.NewClientBuilder().WithObjects(replica).Build(), source)
+	assert.NoError(t, l.wait(t.Context(), source))
+
+	ctx, cancel := context.WithTimeout(t.Context(), 10*time.Millisecond)
+	defer cancel()
+
+	// reads are not limited, only writes wait for the limiter
+	assert.NoError(t, c.Get(ctx, client.ObjectKeyFromObject(replica), &corev1.ConfigMap{}))
+	assert.Error(t, c.Update(ctx, replica))
+}
@@ -1,18 +1,36 @@
 package replicator
 
 import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 const (
 	SourceNameLabel      = "replicator.c0deltin.dev/source-name"
 	SourceNamespaceLabel = "replicator.c0deltin.dev/source-namespace"
+	PolicyLabel          = "replicator.c0deltin.dev/policy"
+	ClusterPolicyLabel   = "replicator.c0deltin.dev/cluster-policy"
 
 	ReplicationAllowedAnnotation = "replik8or.c0deltin.dev/replication-allowed"
 	DesiredNamespacesAnnotation  = "replik8or.c0deltin.dev/desired-namespaces"
 
-	LastReplicationAnnotation = "replik8or.c0deltin.dev/last-replication"
-	SourceVersionAnnotation   = "replik8or.c0deltin.dev/source-version"
+	ReplicationAllowedNamespacesAnnotation = "replik8or.c0deltin.dev/replication-allowed-namespaces"
+	ReplicateFromAnnotation                = "replik8or.c0deltin.dev/replicate-from"
+	RunAsAnnotation                        = "replik8or.c0deltin.dev/run-as"
+
+	LastReplicationAnnotation   = "replik8or.c0deltin.dev/last-replication"
+	ReplicatedToAnnotation      = "replik8or.c0deltin.dev/replicated-to"
+	FailedNamespacesAnnotation  = "replik8or.c0deltin.dev/failed-namespaces"
+	ReplicationErrorsAnnotation = "replik8or.c0deltin.dev/replication-errors"
+	ConflictsAnnotation         = "replik8or.c0deltin.dev/conflicts"
+	ContentHashAnnotation       = "replik8or.c0deltin.dev/content-hash"
+	KeyFilterAnnotation         = "replik8or.c0deltin.dev/key-filter"
 )
 
 func HasAnnotations(object client.Object, annotations ...string) bool {
@@ -23,6 +41,81 @@ func HasLabels(object client.Object, labels ...string) bool {
 	return matchingItems(object.GetLabels(), labels...)
 }
 
+// IsReplicaOf reports whether object is labeled as replica of source.
+func IsReplicaOf(object, source client.Object) bool {
+	labels := object.GetLabels()
+	return labels[SourceNamespaceLabel] == source.GetNamespace() && labels[SourceNameLabel] == source.GetName()
+}
+
+// AllowsPullFrom reports whether source allows objects of namespace to pull its data. The allowed namespaces are
+// listed by name or pattern (see path.Match) in the ReplicationAllowedNamespacesAnnotation.
+func AllowsPullFrom(source client.Object, namespace string) bool {
+	for _, pattern := range strings.Split(source.GetAnnotations()[ReplicationAllowedNamespacesAnnotation], ",") {
+		if ok, _ := path.Match(strings.TrimSpace(pattern), namespace); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseReplicateFrom parses the "namespace/name" reference of the ReplicateFromAnnotation.
+func ParseReplicateFrom(value string) (client.ObjectKey, error) {
+	namespace, name, ok := strings.Cut(strings.TrimSpace(value), "/")
+	if !ok || namespace == "" || name == "" || strings.Contains(name, "/") {
+		return client.ObjectKey{}, fmt.Errorf("expected <namespace>/<name>, got %q", value)
+	}
+	return client.ObjectKey{Namespace: namespace, Name: name}, nil
+}
+
+// IsPolicyReplica reports whether object is a replica managed by a ReplicationPolicy or ClusterReplicationPolicy.
+func IsPolicyReplica(object client.Object) bool {
+	_, rank := managedBy(object)
+	return rank > 0
+}
+
+// managedBy returns the policy managing object and its precedence. Replicas of a ClusterReplicationPolicy take
+// precedence over the ones of a ReplicationPolicy, which take precedence over replicas created by annotation.
+func managedBy(object client.Object) (string, int) {
+	labels := object.GetLabels()
+	if name, ok := labels[ClusterPolicyLabel]; ok {
+		return "ClusterReplicationPolicy/" + name, 2
+	}
+	if name, ok := labels[PolicyLabel]; ok {
+		return "ReplicationPolicy/" + name, 1
+	}
+	return "", 0
+}
+
+// IsOutdated reports whether replica was written from another content of source.
+func IsOutdated(replica, source client.Object) bool {
+	hash, err := ContentHash(source)
+	return err != nil || replica.GetAnnotations()[ContentHashAnnotation] != hash
+}
+
+// contentFields are the top-level fields of ConfigMaps and Secrets making up their replicated content.
+var contentFields = []string{"f:data", "f:binaryData", "f:stringData", "f:type", "f:immutable"}
+
+// ContentModified returns the time the content of the object was last written, derived from the managed fields of
+// the managers owning its content. Writes of other fields by other managers, e.g. the status annotations and
+// finalizers of replik8or, are ignored. Objects without such managed fields fall back to their creation time.
+func ContentModified(object client.Object) time.Time {
+	contentModified := object.GetCreationTimestamp().Time
+	for _, entry := range object.GetManagedFields() {
+		if entry.Time == nil || !entry.Time.After(contentModified) || entry.FieldsV1 == nil {
+			continue
+		}
+
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(entry.FieldsV1.Raw, &fields); err != nil {
+			continue
+		}
+		if slices.ContainsFunc(contentFields, func(field string) bool { _, ok := fields[field]; return ok }) {
+			contentModified = entry.Time.Time
+		}
+	}
+	return contentModified
+}
+
 func matchingItems(m map[string]string, items ...string) bool {
 	var matching int
 	for _, label := range items {
@@ -2,10 +2,14 @@ package replicator
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 func TestHasAnnotations(t *testing.T) {
@@ -43,3 +47,101 @@ func TestHasLabels(t *testing.T) {
 		assert.False(t, HasLabels(object, SourceNameLabel, SourceNamespaceLabel, "unknown"))
 	})
 }
+
+func TestIsReplicaOf(t *testing.T) {
+	source := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "source-name", Namespace: "source-namespace"}}
+
+	t.Run("replica", func(t *testing.T) {
+		object := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{
+					SourceNameLabel:      "source-name",
+					SourceNamespaceLabel: "source-namespace",
+				},
+			},
+		}
+		assert.True(t, IsReplicaOf(object, source))
+	})
+	t.Run("replica of another source", func(t *testing.T) {
+		object := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{
+					SourceNameLabel:      "source-name",
+					SourceNamespaceLabel: "another-namespace",
+				},
+			},
+		}
+		assert.False(t, IsReplicaOf(object, source))
+	})
+	t.Run("no replica", func(t *testing.T) {
+		assert.False(t, IsReplicaOf(&corev1.ConfigMap{}, source))
+	})
+}
+
+func TestContentModified(t *testing.T) {
+	created := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	dataChanged := created.Add(time.Hour)
+	statusChanged := created.Add(2 * time.Hour)
+
+	object := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			CreationTimestamp: metav1.NewTime(created),
+			ManagedFields: []metav1.ManagedFieldsEntry{
+				{
+					Manager:  "kubectl",
+					Time:     ptr.To(metav1.NewTime(dataChanged)),
+					FieldsV1: &metav1.FieldsV1{Raw: []byte(`{"f:data":{"f:foo":{}}}`)},
+				},
+				{
+					Manager:  "replik8or",
+					Time:     ptr.To(metav1.NewTime(statusChanged)),
+					FieldsV1: &metav1.FieldsV1{Raw: []byte(`{"f:metadata":{"f:annotations":{}}}`)},
+				},
+			},
+		},
+	}
+	assert.Equal(t, dataChanged, ContentModified(object))
+}
+
+func TestIsOutdated(t *testing.T) {
+	source := &corev1.ConfigMap{Data: map[string]string{"foo": "bar"}}
+	hash, err := ContentHash(source)
+	require.NoError(t, err)
+
+	t.Run("up to date", func(t *testing.T) {
+		replica := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{ContentHashAnnotation: hash}},
+		}
+		assert.False(t, IsOutdated(replica, source))
+	})
+	t.Run("outdated", func(t *testing.T) {
+		replica := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{ContentHashAnnotation: "outdated"}},
+		}
+		assert.True(t, IsOutdated(replica, source))
+	})
+}
+
+func TestAllowsPullFrom(t *testing.T) {
+	source := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Annotations: map[string]string{ReplicationAllowedNamespacesAnnotation: "ci, team-*"},
+		},
+	}
+
+	assert.True(t, AllowsPullFrom(source, "ci"))
+	assert.True(t, AllowsPullFrom(source, "team-a"))
+	assert.False(t, AllowsPullFrom(source, "other"))
+	assert.False(t, AllowsPullFrom(&corev1.ConfigMap{}, "ci"))
+}
+
+func TestParseReplicateFrom(t *testing.T) {
+	key, err := ParseReplicateFrom(" infra/ca-bundle ")
+	assert.NoError(t, err)
+	assert.Equal(t, client.ObjectKey{Namespace: "infra", Name: "ca-bundle"}, key)
+
+	for _, value := range []string{"", "ca-bundle", "/ca-bundle", "infra/", "infra/ca/bundle"} {
+		_, err := ParseReplicateFrom(value)
+		assert.Error(t, err, value)
+	}
+}
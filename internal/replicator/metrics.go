@@ -0,0 +1,59 @@
+package replicator
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+var (
+	replicaOperations = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "replik8or_replica_operations_total",
+		Help: "Number of replicas created, updated or deleted.",
+	}, []string{"kind", "operation", "dry_run"})
+
+	replicationFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "replik8or_replication_failures_total",
+		Help: "Number of failed replica writes by reason.",
+	}, []string{"kind", "reason"})
+
+	replicaConflicts = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "replik8or_replica_conflicts_total",
+		Help: "Number of replicas not written because the target is taken by an object that is not a replica.",
+	}, []string{"kind"})
+
+	replicationLag = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "replik8or_replication_lag_seconds",
+		Help:    "Time between the last content change of a source and the update of its outdated replica.",
+		Buckets: prometheus.ExponentialBuckets(0.1, 2, 12),
+	}, []string{"kind"})
+)
+
+func init() {
+	metrics.Registry.MustRegister(replicaOperations, replicationFailures, replicaConflicts, replicationLag)
+}
+
+func observeOperation(object client.Object, operation string, dryRun bool) {
+	replicaOperations.WithLabelValues(Kind(object), operation, strconv.FormatBool(dryRun)).Inc()
+}
+
+func observeFailure(object client.Object, err error) {
+	reason := string(apierrors.ReasonForError(err))
+	if reason == "" {
+		reason = string(metav1.StatusReasonUnknown)
+	}
+	replicationFailures.WithLabelValues(Kind(object), reason).Inc()
+}
+
+func observeConflict(object client.Object) {
+	replicaConflicts.WithLabelValues(Kind(object)).Inc()
+}
+
+func observeLag(source client.Object) {
+	replicationLag.WithLabelValues(Kind(source)).Observe(time.Since(ContentModified(source)).Seconds())
+}
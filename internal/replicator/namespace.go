@@ -5,23 +5,33 @@ import (
 	"slices"
 	"strings"
 
+	"go.opentelemetry.io/otel/trace"
 	corev1 "k8s.io/api/core/v1"
-	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/c0deltin/replik8or/internal/namespaces"
+	"github.com/c0deltin/replik8or/internal/tracing"
 )
 
 // ListTargetNamespaces returns a list of namespace names in which replicas should exist.
 // It respects the annotation of the source object, the namespace of the source object itself which will be ignored
-// and also the namespaces that are disallowed to have replicas by configuration.
-func (r *Replicator[T]) ListTargetNamespaces(ctx context.Context, source T) ([]string, error) {
-	var (
-		targetNamespaces []string
-		err              error
-	)
+// and also the namespaces that are disallowed to have replicas by configuration. Terminating namespaces are never
+// returned. If enabled by configuration, only the namespaces the ServiceAccount of the source is authorized to write
+// to are returned.
+func (r *Replicator[T]) ListTargetNamespaces(
+	ctx context.Context,
+	source T,
+	index *namespaces.Index,
+) (targetNamespaces []string, err error) {
+	ctx, span := tracer.Start(ctx, "Replicator.ListTargetNamespaces",
+		trace.WithAttributes(tracing.SourceAttributes(source, Kind(source))...))
+	defer func() { tracing.End(span, err) }()
+
 	if HasAnnotations(source, DesiredNamespacesAnnotation) {
-		targetNamespaces, err = r.desiredNamespaces(ctx, source)
+		targetNamespaces, err = desiredNamespaces(source, index)
 	} else {
-		targetNamespaces, err = r.clusterNamespaces(ctx)
+		targetNamespaces, err = clusterNamespaces(index)
 	}
 	if err != nil {
 		return nil, err
@@ -31,11 +41,33 @@ func (r *Replicator[T]) ListTargetNamespaces(ctx context.Context, source T) ([]s
 		return source.GetNamespace() == s || slices.Contains(r.config.DisallowedNamespaces, s)
 	})
 
+	if r.config.AuthorizeTargets {
+		return r.AuthorizedNamespaces(ctx, source, targetNamespaces)
+	}
 	return targetNamespaces, nil
 }
 
-// desiredNamespaces extracts the namespaces set on resource by DesiredNamespacesAnnotation and checks for existing.
-func (r *Replicator[T]) desiredNamespaces(ctx context.Context, source T) ([]string, error) {
+// IsTargetNamespace reports whether the existing namespace is one of the namespaces returned by ListTargetNamespaces
+// for source, without listing all namespaces.
+func (r *Replicator[T]) IsTargetNamespace(ctx context.Context, source T, namespace string) (bool, error) {
+	if source.GetNamespace() == namespace || slices.Contains(r.config.DisallowedNamespaces, namespace) {
+		return false, nil
+	}
+
+	if desired, ok := source.GetAnnotations()[DesiredNamespacesAnnotation]; ok &&
+		!slices.Contains(strings.Split(desired, ","), namespace) {
+		return false, nil
+	}
+
+	if r.config.AuthorizeTargets {
+		authorized, err := r.AuthorizedNamespaces(ctx, source, []string{namespace})
+		return len(authorized) > 0, err
+	}
+	return true, nil
+}
+
+// desiredNamespaces returns the active namespaces set on source by DesiredNamespacesAnnotation.
+func desiredNamespaces(source client.Object, index *namespaces.Index) ([]string, error) {
 	namespacesAnnotation, ok := source.GetAnnotations()[DesiredNamespacesAnnotation]
 	if !ok {
 		return nil, nil
@@ -43,29 +75,29 @@ func (r *Replicator[T]) desiredNamespaces(ctx context.Context, source T) ([]stri
 
 	var desiredNamespaces []string
 	for _, desiredNamespace := range strings.Split(namespacesAnnotation, ",") {
-		var namespace corev1.Namespace
-		if err := r.client.Get(ctx, client.ObjectKey{Name: desiredNamespace}, &namespace); err != nil {
-			if apierrors.IsNotFound(err) {
-				continue
-			}
+		namespace, ok, err := index.Get(desiredNamespace)
+		if err != nil {
 			return nil, err
 		}
-		desiredNamespaces = append(desiredNamespaces, namespace.Name)
+		if ok && namespace.Phase == corev1.NamespaceActive {
+			desiredNamespaces = append(desiredNamespaces, namespace.Name)
+		}
 	}
 	return desiredNamespaces, nil
 }
 
-// clusterNamespaces lists all namespaces within the cluster and returns a slice containing their names.
-func (r *Replicator[T]) clusterNamespaces(ctx context.Context) ([]string, error) {
-	var namespaceList corev1.NamespaceList
-	if err := r.client.List(ctx, &namespaceList); err != nil {
+// clusterNamespaces returns the names of all active namespaces within the cluster.
+func clusterNamespaces(index *namespaces.Index) ([]string, error) {
+	namespaceList, err := index.List(labels.Everything())
+	if err != nil {
 		return nil, err
 	}
 
-	var namespaces = make([]string, len(namespaceList.Items))
-	for i := range namespaceList.Items {
-		namespaces[i] = namespaceList.Items[i].Name
+	var names []string
+	for _, namespace := range namespaceList {
+		if namespace.Phase == corev1.NamespaceActive {
+			names = append(names, namespace.Name)
+		}
 	}
-
-	return namespaces, nil
+	return names, nil
 }
@@ -1,32 +1,34 @@
 package replicator
 
 import (
+	"context"
 	"testing"
 
 	"github.com/c0deltin/replik8or/internal/config"
+	"github.com/c0deltin/replik8or/internal/namespaces"
 	"github.com/stretchr/testify/assert"
+	authorizationv1 "k8s.io/api/authorization/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
 )
 
 func TestReplicator_ListTargetNamespaces(t *testing.T) {
-	fakeClient := fake.NewFakeClient()
 	r := Replicator[*corev1.ConfigMap]{
-		client: fakeClient,
 		config: &config.Config{
 			DisallowedNamespaces: []string{"disallowed-by-config"},
 		},
 	}
-
-	err := fakeClient.Create(t.Context(), &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "testing"}})
-	assert.NoError(t, err)
-	err = fakeClient.Create(t.Context(), &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "foo"}})
-	assert.NoError(t, err)
-	err = fakeClient.Create(t.Context(), &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "disallowed-by-config"}})
-	assert.NoError(t, err)
-	err = fakeClient.Create(t.Context(), &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system"}})
-	assert.NoError(t, err)
+	index := namespaces.NewIndex(
+		namespaces.Namespace{Name: "testing"},
+		namespaces.Namespace{Name: "foo"},
+		namespaces.Namespace{Name: "disallowed-by-config"},
+		namespaces.Namespace{Name: "kube-system"},
+		namespaces.Namespace{Name: "terminating", Phase: corev1.NamespaceTerminating},
+	)
 
 	t.Run("desired namespace annotations", func(t *testing.T) {
 		source := &corev1.ConfigMap{
@@ -34,15 +36,15 @@ func TestReplicator_ListTargetNamespaces(t *testing.T) {
 				Name:      "source-name",
 				Namespace: "source-namespaces",
 				Annotations: map[string]string{
-					DesiredNamespacesAnnotation: "testing,source-namespaces,foo,disallowed-by-config",
+					DesiredNamespacesAnnotation: "testing,source-namespaces,foo,disallowed-by-config,terminating",
 				},
 			},
 		}
 
-		namespaces, err := r.ListTargetNamespaces(t.Context(), source)
+		targetNamespaces, err := r.ListTargetNamespaces(t.Context(), source, index)
 
 		assert.NoError(t, err)
-		assert.Equal(t, []string{"testing", "foo"}, namespaces)
+		assert.Equal(t, []string{"testing", "foo"}, targetNamespaces)
 	})
 
 	t.Run("cluster namespaces", func(t *testing.T) {
@@ -53,9 +55,87 @@ func TestReplicator_ListTargetNamespaces(t *testing.T) {
 			},
 		}
 
-		namespaces, err := r.ListTargetNamespaces(t.Context(), source)
+		targetNamespaces, err := r.ListTargetNamespaces(t.Context(), source, index)
 
 		assert.NoError(t, err)
-		assert.Equal(t, []string{"foo", "kube-system", "testing"}, namespaces)
+		assert.Equal(t, []string{"foo", "kube-system", "testing"}, targetNamespaces)
 	})
 }
+
+func TestReplicator_ListTargetNamespaces_authorizeTargets(t *testing.T) {
+	var reviews []authorizationv1.SubjectAccessReviewSpec
+	fakeClient := fake.NewClientBuilder().
+		WithInterceptorFuncs(interceptor.Funcs{
+			Create: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.CreateOption) error {
+				review, ok := obj.(*authorizationv1.SubjectAccessReview)
+				if !ok {
+					return c.Create(ctx, obj, opts...)
+				}
+				reviews = append(reviews, review.Spec)
+				review.Status.Allowed = review.Spec.ResourceAttributes.Namespace == "testing"
+				return nil
+			},
+		}).
+		Build()
+	recorder := record.NewFakeRecorder(1)
+	r := New[*corev1.Secret](fakeClient, fakeClient, &config.Config{AuthorizeTargets: true, DryRun: true}, recorder)
+
+	source := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "source-name",
+			Namespace:   "source-namespace",
+			Annotations: map[string]string{RunAsAnnotation: "replicator"},
+		},
+	}
+
+	index := namespaces.NewIndex(namespaces.Namespace{Name: "testing"}, namespaces.Namespace{Name: "kube-system"})
+
+	targetNamespaces, err := r.ListTargetNamespaces(t.Context(), source, index)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"testing"}, targetNamespaces)
+	assert.Equal(t, "Warning Unauthorized ServiceAccount source-namespace/replicator may not write secrets "+
+		"to namespaces kube-system", <-recorder.Events)
+
+	for _, review := range reviews {
+		assert.Equal(t, "system:serviceaccount:source-namespace:replicator", review.User)
+		assert.Equal(t, "secrets", review.ResourceAttributes.Resource)
+	}
+	// cluster-wide and kube-system are denied on create, testing is reviewed for create and update
+	assert.Len(t, reviews, 4)
+
+	// the outcome of the reviews is reused for the next reconciliation
+	targetNamespaces, err = r.ListTargetNamespaces(t.Context(), source, index)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"testing"}, targetNamespaces)
+	assert.Len(t, reviews, 4)
+}
+
+func TestReplicator_IsTargetNamespace(t *testing.T) {
+	r := Replicator[*corev1.ConfigMap]{
+		config: &config.Config{DisallowedNamespaces: []string{"disallowed-by-config"}},
+	}
+	source := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "source-name", Namespace: "source-namespace"}}
+	desired := source.DeepCopy()
+	desired.Annotations = map[string]string{DesiredNamespacesAnnotation: "testing,foo"}
+
+	tests := []struct {
+		name      string
+		source    *corev1.ConfigMap
+		namespace string
+		expected  bool
+	}{
+		{name: "cluster namespace", source: source, namespace: "testing", expected: true},
+		{name: "source namespace", source: source, namespace: "source-namespace"},
+		{name: "disallowed namespace", source: source, namespace: "disallowed-by-config"},
+		{name: "desired namespace", source: desired, namespace: "foo", expected: true},
+		{name: "not desired namespace", source: desired, namespace: "bar"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ok, err := r.IsTargetNamespace(t.Context(), tt.source, tt.namespace)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, ok)
+		})
+	}
+}
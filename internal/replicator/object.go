@@ -1,6 +1,8 @@
 package replicator
 
 import (
+	"fmt"
+
 	corev1 "k8s.io/api/core/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
@@ -20,3 +22,15 @@ func EmptySecret() *corev1.Secret {
 func EmptySecretList() client.ObjectList {
 	return &corev1.SecretList{}
 }
+
+// Kind returns the kind of the replicated object.
+func Kind(object client.Object) string {
+	switch object.(type) {
+	case *corev1.Secret:
+		return "Secret"
+	case *corev1.ConfigMap:
+		return "ConfigMap"
+	default:
+		return fmt.Sprintf("%T", object)
+	}
+}
@@ -26,3 +26,9 @@ func TestEmptySecretList(t *testing.T) {
 	secretList := EmptySecretList()
 	assert.Equal(t, &corev1.SecretList{}, secretList)
 }
+
+func TestKind(t *testing.T) {
+	assert.Equal(t, "ConfigMap", Kind(EmptyConfigMap()))
+	assert.Equal(t, "Secret", Kind(EmptySecret()))
+	assert.Equal(t, "*v1.Namespace", Kind(&corev1.Namespace{}))
+}
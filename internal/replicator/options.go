@@ -0,0 +1,81 @@
+package replicator
+
+import (
+	"encoding/json"
+	"maps"
+	"path"
+	"slices"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Option modifies a replica after the fields of its source were copied.
+type Option func(replica client.Object)
+
+// WithLabels adds labels to the replica.
+func WithLabels(labels map[string]string) Option {
+	return func(replica client.Object) {
+		merged := maps.Clone(replica.GetLabels())
+		if merged == nil {
+			merged = map[string]string{}
+		}
+		maps.Copy(merged, labels)
+		replica.SetLabels(merged)
+	}
+}
+
+// keyFilter is the filter recorded in the KeyFilterAnnotation of a replica.
+type keyFilter struct {
+	Include []string `json:"include,omitempty"`
+	Exclude []string `json:"exclude,omitempty"`
+}
+
+// WithKeyFilter only keeps the keys of the replica matching one of include (all keys if empty) and none of exclude.
+// Both lists may contain shell file name patterns. The filter is recorded in the KeyFilterAnnotation, as replicas
+// are compared by their metadata and a changed filter must cause a write.
+func WithKeyFilter(include, exclude []string) Option {
+	keep := func(key string, _ []byte) bool {
+		return (len(include) == 0 || matchesAny(key, include)) && !matchesAny(key, exclude)
+	}
+	// encoding string slices never fails
+	filter, _ := json.Marshal(keyFilter{Include: include, Exclude: exclude})
+
+	return func(replica client.Object) {
+		annotations := maps.Clone(replica.GetAnnotations())
+		if annotations == nil {
+			annotations = map[string]string{}
+		}
+		annotations[KeyFilterAnnotation] = string(filter)
+		replica.SetAnnotations(annotations)
+
+		switch v := replica.(type) {
+		case *corev1.Secret:
+			v.Data = filterKeys(v.Data, keep)
+		case *corev1.ConfigMap:
+			v.Data = filterKeys(v.Data, func(key, _ string) bool { return keep(key, nil) })
+			v.BinaryData = filterKeys(v.BinaryData, keep)
+		}
+	}
+}
+
+// filterKeys returns a copy of m only containing the entries keep returns true for.
+func filterKeys[V any](m map[string]V, keep func(string, V) bool) map[string]V {
+	if m == nil {
+		return nil
+	}
+	filtered := make(map[string]V, len(m))
+	for key, value := range m {
+		if keep(key, value) {
+			filtered[key] = value
+		}
+	}
+	return filtered
+}
+
+func matchesAny(key string, patterns []string) bool {
+	return slices.ContainsFunc(patterns, func(pattern string) bool {
+		matched, err := path.Match(pattern, key)
+		return err == nil && matched
+	})
+}
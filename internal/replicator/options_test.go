@@ -0,0 +1,41 @@
+package replicator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestWithLabels(t *testing.T) {
+	replica := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"foo": "bar"}}}
+
+	WithLabels(map[string]string{PolicyLabel: "policy"})(replica)
+
+	assert.Equal(t, map[string]string{"foo": "bar", PolicyLabel: "policy"}, replica.Labels)
+}
+
+func TestWithKeyFilter(t *testing.T) {
+	t.Run("ConfigMap", func(t *testing.T) {
+		data := map[string]string{"foo": "bar", "foo.yaml": "bar", "lorem": "ipsum"}
+		replica := &corev1.ConfigMap{
+			Data:       data,
+			BinaryData: map[string][]byte{"foo.bin": []byte("bar")},
+		}
+
+		WithKeyFilter([]string{"foo*"}, []string{"*.yaml"})(replica)
+
+		assert.Equal(t, map[string]string{"foo": "bar"}, replica.Data)
+		assert.Equal(t, map[string][]byte{"foo.bin": []byte("bar")}, replica.BinaryData)
+		assert.Len(t, data, 3, "the source data must not be modified")
+		assert.Equal(t, `{"include":["foo*"],"exclude":["*.yaml"]}`, replica.Annotations[KeyFilterAnnotation])
+	})
+	t.Run("Secret", func(t *testing.T) {
+		replica := &corev1.Secret{Data: map[string][]byte{"tls.crt": []byte("crt"), "tls.key": []byte("key")}}
+
+		WithKeyFilter(nil, []string{"tls.key"})(replica)
+
+		assert.Equal(t, map[string][]byte{"tls.crt": []byte("crt")}, replica.Data)
+	})
+}
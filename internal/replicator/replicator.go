@@ -2,50 +2,300 @@ package replicator
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"maps"
+	"reflect"
 
 	"github.com/c0deltin/replik8or/internal/config"
+	"github.com/c0deltin/replik8or/internal/tracing"
+	"github.com/go-logr/logr"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	utilcache "k8s.io/apimachinery/pkg/util/cache"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 )
 
+var tracer = otel.Tracer("github.com/c0deltin/replik8or/internal/replicator")
+
+// ErrConflict is returned when the target of a replica is already taken by an object which is not a replica of the
+// source.
+var ErrConflict = errors.New("object exists and is not a replica of the source")
+
+// ErrOverridden is returned when the replica is managed with a higher precedence, e.g. by a ClusterReplicationPolicy
+// while being replicated by annotation.
+var ErrOverridden = errors.New("replica is managed with higher precedence")
+
+// ErrNamespaceTerminating is returned when the replica can not be created as its namespace is being terminated.
+var ErrNamespaceTerminating = errors.New("namespace is terminating")
+
+// ErrNamespaceNotFound is returned when the replica can not be created as its namespace does not exist. This only
+// happens with a static namespace index, which assumes all WATCH_NAMESPACES to exist.
+var ErrNamespaceNotFound = errors.New("namespace does not exist")
+
 type Replicator[T client.Object] struct {
 	client client.Client
-	config *config.Config
+	// reader reads replicas from the API server, as their data is not cached.
+	reader   client.Reader
+	config   *config.Config
+	recorder record.EventRecorder
+
+	// authClient creates the SubjectAccessReviews, which must never be sent as dry-run.
+	authClient client.Client
+	reviews    *utilcache.LRUExpireCache
+	limiter    *writeLimiter
 }
 
-func New[T client.Object](client client.Client, config *config.Config) *Replicator[T] {
-	return &Replicator[T]{
-		client: client,
-		config: config,
+// New returns a Replicator writing replicas through the given client. Replicas are read with reader before they are
+// written. If dry-run is enabled by configuration, all writes are sent to the API server with client.DryRunAll and
+// therefore never persisted.
+func New[T client.Object](
+	c client.Client,
+	reader client.Reader,
+	config *config.Config,
+	recorder record.EventRecorder,
+) *Replicator[T] {
+	r := &Replicator[T]{
+		client:     c,
+		reader:     reader,
+		config:     config,
+		recorder:   recorder,
+		authClient: c,
+		reviews:    utilcache.NewLRUExpireCache(maxReviews),
+		limiter:    newWriteLimiter(config.SourceWriteQPS),
+	}
+	if config.DryRun {
+		r.client = client.NewDryRunClient(c)
 	}
+	return r
 }
 
-func (r *Replicator[T]) CreateOrUpdate(ctx context.Context, source, replica T) error {
-	res, err := controllerutil.CreateOrUpdate(ctx, r.client, replica, func() error {
-		return CopyFields(source, replica)
+// CreateOrUpdate writes replica with the fields of source. The options are applied after the fields were copied.
+func (r *Replicator[T]) CreateOrUpdate(ctx context.Context, source, replica T, opts ...Option) (err error) {
+	ctx, span := r.startSpan(ctx, "Replicator.CreateOrUpdate", source, replica)
+	defer func() { tracing.End(span, err) }()
+
+	var previousHash string
+	res, err := r.createOrUpdate(ctx, source, replica, func(replica T) error {
+		previousHash = replica.GetAnnotations()[ContentHashAnnotation]
+
+		// an existing object without matching source labels is owned by someone else and only overwritten if enabled
+		// by configuration
+		exists := replica.GetResourceVersion() != ""
+		if exists && !IsReplicaOf(replica, source) && !r.config.OverwriteExisting {
+			return ErrConflict
+		}
+		previousOwner, previousRank := managedBy(replica)
+
+		if err := CopyFields(source, replica); err != nil {
+			return err
+		}
+		for _, opt := range opts {
+			opt(replica)
+		}
+
+		// replicas of the same source managed by someone else are only taken over with a higher precedence
+		if owner, rank := managedBy(replica); exists && owner != previousOwner {
+			switch {
+			case rank < previousRank:
+				return ErrOverridden
+			case rank == previousRank:
+				return ErrConflict
+			}
+		}
+		return nil
 	})
 	if err != nil {
+		switch {
+		case errors.Is(err, ErrOverridden):
+			// the replica is in place, just not managed by the caller
+		case errors.Is(err, ErrConflict):
+			observeConflict(replica)
+		case apierrors.HasStatusCause(err, corev1.NamespaceTerminatingCause):
+			// the namespace is removed together with all of its objects
+			err = fmt.Errorf("%w: %w", ErrNamespaceTerminating, err)
+		case isNamespaceNotFound(err):
+			// the namespace is replicated to on the next change or resync of the source once it was created
+			err = fmt.Errorf("%w: %w", ErrNamespaceNotFound, err)
+		default:
+			observeFailure(replica, err)
+		}
 		return fmt.Errorf("create or updating replica: %w", err)
 	}
+	span.SetAttributes(tracing.ResultKey.String(string(res)))
 
-	lgr := log.FromContext(ctx).
+	lgr := r.logger(ctx).
 		WithValues("source", NamespacedName(source), "replica", NamespacedName(replica))
 
 	switch res {
 	case controllerutil.OperationResultCreated:
 		lgr.Info("created replica")
+		r.observeWrite(source, replica, "created", "")
+		r.event(replica, source, replica, "create", EventReasonCreated,
+			"Created from source %s", NamespacedName(source))
 	case controllerutil.OperationResultUpdated:
 		lgr.Info("updated replica")
+		r.observeWrite(source, replica, "updated", previousHash)
+		r.event(replica, source, replica, "update", EventReasonUpdated,
+			"Updated from source %s", NamespacedName(source))
 	default:
 		lgr.Info("replica already in place", "operation", res)
 	}
 	return nil
 }
 
-// CopyFields copy fields of source to replica object.
+// createOrUpdate writes replica using mutate, unless the cached replica already carries the labels and annotations
+// set by mutate. Replicas are cached without their data, so they are compared by their ContentHashAnnotation and only
+// read from the API server if they are written.
+func (r *Replicator[T]) createOrUpdate(
+	ctx context.Context,
+	source, replica T,
+	mutate func(replica T) error,
+) (controllerutil.OperationResult, error) {
+	err := r.client.Get(ctx, client.ObjectKeyFromObject(replica), replica)
+	if client.IgnoreNotFound(err) != nil {
+		return controllerutil.OperationResultNone, err
+	}
+	if err == nil {
+		desired := replica.DeepCopyObject().(T)
+		if err := mutate(desired); err != nil {
+			return controllerutil.OperationResultNone, err
+		}
+		if reflect.DeepEqual(replica.GetLabels(), desired.GetLabels()) &&
+			reflect.DeepEqual(replica.GetAnnotations(), desired.GetAnnotations()) {
+			return controllerutil.OperationResultNone, nil
+		}
+	}
+
+	c := readerClient{Client: r.limiter.client(r.client, source), reader: r.reader}
+	return controllerutil.CreateOrUpdate(ctx, c, replica, func() error {
+		return mutate(replica)
+	})
+}
+
+// readerClient is a client.Client reading objects with reader instead of the cache of the client.
+type readerClient struct {
+	client.Client
+	reader client.Reader
+}
+
+func (c readerClient) Get(ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+	return c.reader.Get(ctx, key, obj, opts...)
+}
+
+// Pull copies the data of source into target. Unlike a replica, target is owned by its creator, which requested the
+// data of source, so neither its labels nor its other annotations are touched.
+func (r *Replicator[T]) Pull(ctx context.Context, source, target T) (err error) {
+	ctx, span := r.startSpan(ctx, "Replicator.Pull", source, target)
+	defer func() { tracing.End(span, err) }()
+
+	previousHash := target.GetAnnotations()[ContentHashAnnotation]
+	changed, err := CopyData(source, target)
+	if err != nil {
+		return err
+	}
+	if !changed {
+		span.SetAttributes(tracing.ResultKey.String(string(controllerutil.OperationResultNone)))
+		return nil
+	}
+
+	if err := r.limiter.client(r.client, source).Update(ctx, target); err != nil {
+		observeFailure(target, err)
+		return fmt.Errorf("updating target: %w", err)
+	}
+	span.SetAttributes(tracing.ResultKey.String(string(controllerutil.OperationResultUpdated)))
+
+	r.logger(ctx).
+		WithValues("source", NamespacedName(source), "target", NamespacedName(target)).
+		Info("pulled data into target")
+	r.observeWrite(source, target, "updated", previousHash)
+	r.event(target, source, target, "update", EventReasonUpdated,
+		"Updated from source %s", NamespacedName(source))
+	return nil
+}
+
+// Delete removes the given replica of source.
+func (r *Replicator[T]) Delete(ctx context.Context, source, replica client.Object) (err error) {
+	ctx, span := r.startSpan(ctx, "Replicator.Delete", source, replica)
+	defer func() { tracing.End(span, err) }()
+
+	if err := r.limiter.client(r.client, source).Delete(ctx, replica); err != nil {
+		observeFailure(replica, err)
+		return fmt.Errorf("deleting replica: %w", err)
+	}
+	observeOperation(replica, "deleted", r.config.DryRun)
+	span.SetAttributes(tracing.ResultKey.String("deleted"))
+
+	r.logger(ctx).
+		WithValues("source", NamespacedName(source), "replica", NamespacedName(replica)).
+		Info("deleted replica")
+	r.event(source, source, replica, "delete", EventReasonDeleted,
+		"Deleted replica %s", NamespacedName(replica))
+	return nil
+}
+
+// Forget releases the resources kept for source, e.g. after it was deleted.
+func (r *Replicator[T]) Forget(source client.ObjectKey) {
+	r.limiter.forget(source)
+}
+
+// isNamespaceNotFound reports whether err rejects a create, as the namespace of the object does not exist.
+func isNamespaceNotFound(err error) bool {
+	var status apierrors.APIStatus
+	if !apierrors.IsNotFound(err) || !errors.As(err, &status) {
+		return false
+	}
+	details := status.Status().Details
+	return details != nil && details.Kind == "namespaces"
+}
+
+// startSpan starts a span for an operation on replica.
+func (r *Replicator[T]) startSpan(ctx context.Context, name string, source, replica client.Object) (context.Context, trace.Span) {
+	attributes := append(
+		tracing.SourceAttributes(source, Kind(source)),
+		tracing.TargetNamespaceKey.String(replica.GetNamespace()),
+	)
+	return tracer.Start(ctx, name, trace.WithAttributes(attributes...))
+}
+
+// observeWrite records the metrics of a created or updated replica, which was previously written from the content
+// with previousHash. The replication lag is only observed for writes which actually happened and replicated a changed
+// content of source. Writes repairing a modified replica or creating a replica in a new namespace are not caused by a
+// change of the source.
+func (r *Replicator[T]) observeWrite(source, replica client.Object, operation, previousHash string) {
+	observeOperation(replica, operation, r.config.DryRun)
+	if !r.config.DryRun && previousHash != "" && previousHash != replica.GetAnnotations()[ContentHashAnnotation] {
+		observeLag(source)
+	}
+}
+
+// event records an event about the replica on object. In dry-run mode the replica might not exist, so an event
+// describing the operation that would have been performed is recorded on the source instead.
+func (r *Replicator[T]) event(object, source, replica client.Object, operation, reason, messageFmt string, args ...any) {
+	if r.config.DryRun {
+		r.recorder.Eventf(source, corev1.EventTypeNormal, EventReasonDryRun,
+			"Would %s replica %s", operation, NamespacedName(replica))
+		return
+	}
+	r.recorder.Eventf(object, corev1.EventTypeNormal, reason, messageFmt, args...)
+}
+
+// logger returns the logger of ctx, marking every line when running in dry-run mode.
+func (r *Replicator[T]) logger(ctx context.Context) logr.Logger {
+	lgr := log.FromContext(ctx)
+	if r.config.DryRun {
+		lgr = lgr.WithValues("dryRun", true)
+	}
+	return lgr
+}
+
+// CopyFields copy fields of source to replica object. The replica is annotated with the content hash of source, so
+// replicas of an unchanged source stay untouched and no write is sent for them.
 func CopyFields(source, replica client.Object) error {
 	switch v := replica.(type) {
 	case *corev1.Secret:
@@ -61,14 +311,47 @@ func CopyFields(source, replica client.Object) error {
 	}
 
 	copyLabels(source, replica)
-	copyAnnotations(source, replica)
+	return copyAnnotations(source, replica)
+}
 
-	return nil
+// CopyData copies the data of source to target and sets the content hash of the source object. It reports whether
+// target changed.
+func CopyData(source, target client.Object) (bool, error) {
+	var changed bool
+	switch v := target.(type) {
+	case *corev1.Secret:
+		s := source.(*corev1.Secret)
+		changed = !reflect.DeepEqual(v.Data, s.Data)
+		v.Data = s.Data
+	case *corev1.ConfigMap:
+		s := source.(*corev1.ConfigMap)
+		changed = !reflect.DeepEqual(v.Data, s.Data) || !reflect.DeepEqual(v.BinaryData, s.BinaryData)
+		v.Data = s.Data
+		v.BinaryData = s.BinaryData
+	default:
+		return false, fmt.Errorf("type %T not implemented", v)
+	}
+
+	hash, err := ContentHash(source)
+	if err != nil {
+		return false, err
+	}
+	annotations := maps.Clone(target.GetAnnotations())
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	if annotations[ContentHashAnnotation] != hash {
+		annotations[ContentHashAnnotation] = hash
+		changed = true
+	}
+	target.SetAnnotations(annotations)
+
+	return changed, nil
 }
 
 // copyLabels copies the source labels to the replica and sets a reference to the source object.
 func copyLabels(source, replica client.Object) {
-	labels := source.GetLabels()
+	labels := maps.Clone(source.GetLabels())
 	if labels == nil {
 		labels = map[string]string{}
 	}
@@ -77,18 +360,37 @@ func copyLabels(source, replica client.Object) {
 	replica.SetLabels(labels)
 }
 
-// copyAnnotations copies the source annotations to the replica, removes the replication annotations and set the
-// replicated resourceVersion of the source object.
-func copyAnnotations(source, replica client.Object) {
-	annotations := source.GetAnnotations()
+// copyAnnotations copies the source annotations to the replica, removes the replication and status annotations and
+// sets the content hash of the source object.
+func copyAnnotations(source, replica client.Object) error {
+	hash, err := ContentHash(source)
+	if err != nil {
+		return err
+	}
+	annotations := replicatedAnnotations(source)
 	if annotations == nil {
 		annotations = map[string]string{}
 	}
+	annotations[ContentHashAnnotation] = hash
+	replica.SetAnnotations(annotations)
+	return nil
+}
+
+// replicatedAnnotations returns the annotations of source without the replication and status annotations.
+func replicatedAnnotations(source client.Object) map[string]string {
+	annotations := maps.Clone(source.GetAnnotations())
 	delete(annotations, ReplicationAllowedAnnotation)
 	delete(annotations, DesiredNamespacesAnnotation)
-	// annotations[LastReplicationAnnotation] = time.Now().Format(time.RFC3339)
-	annotations[SourceVersionAnnotation] = source.GetResourceVersion()
-	replica.SetAnnotations(annotations)
+	delete(annotations, ReplicationAllowedNamespacesAnnotation)
+	delete(annotations, RunAsAnnotation)
+	delete(annotations, LastReplicationAnnotation)
+	delete(annotations, ReplicatedToAnnotation)
+	delete(annotations, FailedNamespacesAnnotation)
+	delete(annotations, ReplicationErrorsAnnotation)
+	delete(annotations, ConflictsAnnotation)
+	delete(annotations, ContentHashAnnotation)
+	delete(annotations, KeyFilterAnnotation)
+	return annotations
 }
 
 func NamespacedName(object client.Object) client.ObjectKey {
@@ -1,14 +1,24 @@
 package replicator
 
 import (
+	"context"
+	"errors"
 	"reflect"
 	"testing"
 
+	"github.com/c0deltin/replik8or/internal/config"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/utils/ptr"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
 )
 
 func TestCopyFields(t *testing.T) {
@@ -43,7 +53,7 @@ func TestCopyFields(t *testing.T) {
 		expected.Namespace = "testing" // reset (namespace is usually set before CopyFields is called)
 		expected.Labels[SourceNamespaceLabel] = source.GetNamespace()
 		expected.Labels[SourceNameLabel] = source.GetName()
-		expected.Annotations[SourceVersionAnnotation] = source.GetResourceVersion()
+		expected.Annotations[ContentHashAnnotation], _ = ContentHash(source)
 
 		assert.NoError(t, err)
 		assert.True(t, reflect.DeepEqual(&expected, &replica))
@@ -81,7 +91,7 @@ func TestCopyFields(t *testing.T) {
 		expected.Namespace = "testing" // reset (namespace is usually set before CopyFields is called)
 		expected.Labels[SourceNamespaceLabel] = source.GetNamespace()
 		expected.Labels[SourceNameLabel] = source.GetName()
-		expected.Annotations[SourceVersionAnnotation] = source.GetResourceVersion()
+		expected.Annotations[ContentHashAnnotation], _ = ContentHash(source)
 
 		assert.NoError(t, err)
 		assert.True(t, reflect.DeepEqual(&expected, &replica))
@@ -108,3 +118,342 @@ func TestNamespacedName(t *testing.T) {
 
 	assert.Equal(t, client.ObjectKey{Namespace: "default", Name: "configmap"}, objectKey)
 }
+
+func TestReplicator_CreateOrUpdate(t *testing.T) {
+	source := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "source-name",
+			Namespace: "source-namespace",
+		},
+		Data: map[string]string{
+			"foo": "bar",
+		},
+	}
+
+	t.Run("create replica", func(t *testing.T) {
+		fakeClient := fake.NewFakeClient()
+		recorder := record.NewFakeRecorder(1)
+		r := New[*corev1.ConfigMap](fakeClient, fakeClient, &config.Config{}, recorder)
+
+		replica := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: source.Name, Namespace: "testing"}}
+		err := r.CreateOrUpdate(t.Context(), source, replica)
+		assert.NoError(t, err)
+
+		var actual corev1.ConfigMap
+		err = fakeClient.Get(t.Context(), client.ObjectKeyFromObject(replica), &actual)
+		assert.NoError(t, err)
+		assert.Equal(t, source.Data, actual.Data)
+		assert.Equal(t, "Normal Created Created from source source-namespace/source-name", <-recorder.Events)
+	})
+
+	t.Run("conflict", func(t *testing.T) {
+		existing := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: source.Name, Namespace: "testing"}}
+		fakeClient := fake.NewFakeClient(existing)
+		r := New[*corev1.ConfigMap](fakeClient, fakeClient, &config.Config{}, &record.FakeRecorder{})
+
+		replica := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: source.Name, Namespace: "testing"}}
+		err := r.CreateOrUpdate(t.Context(), source, replica)
+		assert.ErrorIs(t, err, ErrConflict)
+	})
+
+	t.Run("overwrite existing", func(t *testing.T) {
+		existing := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: source.Name, Namespace: "testing"}}
+		fakeClient := fake.NewFakeClient(existing)
+		r := New[*corev1.ConfigMap](fakeClient, fakeClient, &config.Config{OverwriteExisting: true}, &record.FakeRecorder{})
+
+		replica := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: source.Name, Namespace: "testing"}}
+		err := r.CreateOrUpdate(t.Context(), source, replica)
+		assert.NoError(t, err)
+
+		var actual corev1.ConfigMap
+		err = fakeClient.Get(t.Context(), client.ObjectKeyFromObject(replica), &actual)
+		assert.NoError(t, err)
+		assert.Equal(t, source.Data, actual.Data)
+		assert.True(t, IsReplicaOf(&actual, source))
+	})
+
+	// replicas are cached without their data
+	hash, err := ContentHash(source)
+	require.NoError(t, err)
+	cachedReplica := func(contentHash string) *corev1.ConfigMap {
+		return &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      source.Name,
+				Namespace: "testing",
+				Labels: map[string]string{
+					SourceNamespaceLabel: source.Namespace,
+					SourceNameLabel:      source.Name,
+				},
+				Annotations: map[string]string{ContentHashAnnotation: contentHash},
+			},
+		}
+	}
+
+	t.Run("cached replica up-to-date", func(t *testing.T) {
+		fakeClient := fake.NewFakeClient(cachedReplica(hash))
+		reader := fake.NewClientBuilder().
+			WithInterceptorFuncs(interceptor.Funcs{
+				Get: func(ctx context.Context, c client.WithWatch, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+					return errors.New("replica must not be read from the API server")
+				},
+			}).
+			Build()
+		r := New[*corev1.ConfigMap](fakeClient, reader, &config.Config{}, &record.FakeRecorder{})
+
+		replica := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: source.Name, Namespace: "testing"}}
+		err := r.CreateOrUpdate(t.Context(), source, replica)
+		assert.NoError(t, err)
+
+		var actual corev1.ConfigMap
+		err = fakeClient.Get(t.Context(), client.ObjectKeyFromObject(replica), &actual)
+		assert.NoError(t, err)
+		assert.Nil(t, actual.Data, "the replica must not be written")
+	})
+
+	t.Run("cached replica outdated", func(t *testing.T) {
+		outdated := cachedReplica("outdated")
+		fakeClient := fake.NewFakeClient(outdated)
+		full := outdated.DeepCopy()
+		full.Data = map[string]string{"foo": "outdated", "lorem": "ipsum"}
+		var reads int
+		reader := fake.NewClientBuilder().
+			WithObjects(full).
+			WithInterceptorFuncs(interceptor.Funcs{
+				Get: func(ctx context.Context, c client.WithWatch, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+					reads++
+					return c.Get(ctx, key, obj, opts...)
+				},
+			}).
+			Build()
+		r := New[*corev1.ConfigMap](fakeClient, reader, &config.Config{}, &record.FakeRecorder{})
+
+		replica := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: source.Name, Namespace: "testing"}}
+		err := r.CreateOrUpdate(t.Context(), source, replica)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, reads)
+
+		var actual corev1.ConfigMap
+		err = fakeClient.Get(t.Context(), client.ObjectKeyFromObject(replica), &actual)
+		assert.NoError(t, err)
+		assert.Equal(t, source.Data, actual.Data)
+		assert.Equal(t, hash, actual.Annotations[ContentHashAnnotation])
+	})
+
+	t.Run("dry-run", func(t *testing.T) {
+		fakeClient := fake.NewFakeClient()
+		r := New[*corev1.ConfigMap](fakeClient, fakeClient, &config.Config{DryRun: true}, &record.FakeRecorder{})
+
+		replica := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: source.Name, Namespace: "testing"}}
+		err := r.CreateOrUpdate(t.Context(), source, replica)
+		assert.NoError(t, err)
+
+		var actual corev1.ConfigMap
+		err = fakeClient.Get(t.Context(), client.ObjectKeyFromObject(replica), &actual)
+		assert.True(t, apierrors.IsNotFound(err))
+	})
+
+	t.Run("terminating namespace", func(t *testing.T) {
+		fakeClient := fake.NewClientBuilder().
+			WithInterceptorFuncs(interceptor.Funcs{
+				Create: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.CreateOption) error {
+					err := apierrors.NewForbidden(corev1.Resource("configmaps"), obj.GetName(),
+						errors.New("namespace is being terminated"))
+					err.ErrStatus.Details.Causes = []metav1.StatusCause{{Type: corev1.NamespaceTerminatingCause}}
+					return err
+				},
+			}).
+			Build()
+		r := New[*corev1.ConfigMap](fakeClient, fakeClient, &config.Config{}, &record.FakeRecorder{})
+
+		replica := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: source.Name, Namespace: "testing"}}
+		err := r.CreateOrUpdate(t.Context(), source, replica)
+		assert.ErrorIs(t, err, ErrNamespaceTerminating)
+	})
+
+	t.Run("missing namespace", func(t *testing.T) {
+		fakeClient := fake.NewClientBuilder().
+			WithInterceptorFuncs(interceptor.Funcs{
+				Create: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.CreateOption) error {
+					return apierrors.NewNotFound(corev1.Resource("namespaces"), obj.GetNamespace())
+				},
+			}).
+			Build()
+		r := New[*corev1.ConfigMap](fakeClient, fakeClient, &config.Config{}, &record.FakeRecorder{})
+
+		replica := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: source.Name, Namespace: "missing"}}
+		err := r.CreateOrUpdate(t.Context(), source, replica)
+		assert.ErrorIs(t, err, ErrNamespaceNotFound)
+	})
+}
+
+func TestReplicator_CreateOrUpdate_lag(t *testing.T) {
+	source := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "lag-source", Namespace: "source-namespace"},
+		Data:       map[string]string{"foo": "bar"},
+	}
+	hash, err := ContentHash(source)
+	require.NoError(t, err)
+
+	replica := func(contentHash string, data map[string]string) *corev1.ConfigMap {
+		return &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      source.Name,
+				Namespace: "testing",
+				Labels: map[string]string{
+					SourceNamespaceLabel: source.Namespace,
+					SourceNameLabel:      source.Name,
+				},
+				Annotations: map[string]string{ContentHashAnnotation: contentHash},
+			},
+			Data: data,
+		}
+	}
+
+	tests := []struct {
+		name     string
+		existing []client.Object
+		observed bool
+	}{
+		{name: "new replica"},
+		{name: "modified replica", existing: []client.Object{replica(hash, map[string]string{"foo": "modified"})}},
+		{name: "outdated replica", existing: []client.Object{replica("outdated", nil)}, observed: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fakeClient := fake.NewClientBuilder().WithObjects(tt.existing...).Build()
+			r := New[*corev1.ConfigMap](fakeClient, fakeClient, &config.Config{}, &record.FakeRecorder{})
+			before := lagSamples(t)
+
+			err := r.CreateOrUpdate(t.Context(), source,
+				&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: source.Name, Namespace: "testing"}})
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.observed, lagSamples(t) > before)
+		})
+	}
+}
+
+// lagSamples returns the number of replication lags observed for ConfigMaps.
+func lagSamples(t *testing.T) uint64 {
+	var metric dto.Metric
+	require.NoError(t, replicationLag.WithLabelValues("ConfigMap").(prometheus.Histogram).Write(&metric))
+	return metric.GetHistogram().GetSampleCount()
+}
+
+func TestReplicator_Delete(t *testing.T) {
+	source := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "source-name", Namespace: "source-namespace"}}
+	replica := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "source-name", Namespace: "testing"}}
+
+	t.Run("delete replica", func(t *testing.T) {
+		fakeClient := fake.NewFakeClient(replica.DeepCopy())
+		r := New[*corev1.ConfigMap](fakeClient, fakeClient, &config.Config{}, &record.FakeRecorder{})
+
+		err := r.Delete(t.Context(), source, replica.DeepCopy())
+		assert.NoError(t, err)
+
+		err = fakeClient.Get(t.Context(), client.ObjectKeyFromObject(replica), &corev1.ConfigMap{})
+		assert.True(t, apierrors.IsNotFound(err))
+	})
+
+	t.Run("dry-run", func(t *testing.T) {
+		fakeClient := fake.NewFakeClient(replica.DeepCopy())
+		r := New[*corev1.ConfigMap](fakeClient, fakeClient, &config.Config{DryRun: true}, &record.FakeRecorder{})
+
+		err := r.Delete(t.Context(), source, replica.DeepCopy())
+		assert.NoError(t, err)
+
+		err = fakeClient.Get(t.Context(), client.ObjectKeyFromObject(replica), &corev1.ConfigMap{})
+		assert.NoError(t, err)
+	})
+}
+
+func TestReplicator_CreateOrUpdate_precedence(t *testing.T) {
+	source := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "source-name", Namespace: "source-namespace"}}
+	replicaOf := func(labels map[string]string) *corev1.ConfigMap {
+		labels[SourceNamespaceLabel] = source.Namespace
+		labels[SourceNameLabel] = source.Name
+		return &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: source.Name, Namespace: "testing", Labels: labels}}
+	}
+
+	tests := []struct {
+		name     string
+		existing *corev1.ConfigMap
+		opts     []Option
+		expected error
+	}{
+		{
+			name:     "policy takes over annotation replica",
+			existing: replicaOf(map[string]string{}),
+			opts:     []Option{WithLabels(map[string]string{PolicyLabel: "policy"})},
+		},
+		{
+			name:     "cluster policy takes over policy replica",
+			existing: replicaOf(map[string]string{PolicyLabel: "policy"}),
+			opts:     []Option{WithLabels(map[string]string{ClusterPolicyLabel: "cluster-policy"})},
+		},
+		{
+			name:     "annotation is overridden by policy",
+			existing: replicaOf(map[string]string{PolicyLabel: "policy"}),
+			expected: ErrOverridden,
+		},
+		{
+			name:     "policy is overridden by cluster policy",
+			existing: replicaOf(map[string]string{ClusterPolicyLabel: "cluster-policy"}),
+			opts:     []Option{WithLabels(map[string]string{PolicyLabel: "policy"})},
+			expected: ErrOverridden,
+		},
+		{
+			name:     "policies of same precedence conflict",
+			existing: replicaOf(map[string]string{PolicyLabel: "policy"}),
+			opts:     []Option{WithLabels(map[string]string{PolicyLabel: "other-policy"})},
+			expected: ErrConflict,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fakeClient := fake.NewFakeClient(tt.existing)
+			r := New[*corev1.ConfigMap](fakeClient, fakeClient, &config.Config{}, &record.FakeRecorder{})
+
+			replica := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: source.Name, Namespace: "testing"}}
+			err := r.CreateOrUpdate(t.Context(), source, replica, tt.opts...)
+			if tt.expected != nil {
+				assert.ErrorIs(t, err, tt.expected)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestCopyData(t *testing.T) {
+	source := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "source-name", Namespace: "source-namespace", ResourceVersion: "123"},
+		Data:       map[string][]byte{"foo": []byte("bar")},
+	}
+	target := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "target",
+			Namespace:   "testing",
+			Labels:      map[string]string{"app": "foo"},
+			Annotations: map[string]string{ReplicateFromAnnotation: "source-namespace/source-name"},
+		},
+	}
+
+	changed, err := CopyData(source, target)
+	assert.NoError(t, err)
+	assert.True(t, changed)
+	assert.Equal(t, source.Data, target.Data)
+	assert.Equal(t, map[string]string{"app": "foo"}, target.Labels)
+	assert.NotEmpty(t, target.Annotations[ContentHashAnnotation])
+
+	changed, err = CopyData(source, target)
+	assert.NoError(t, err)
+	assert.False(t, changed)
+
+	// metadata only relevant to the source does not change the target
+	source.ResourceVersion = "124"
+	source.Finalizers = []string{"replik8or.c0deltin.dev/source"}
+	changed, err = CopyData(source, target)
+	assert.NoError(t, err)
+	assert.False(t, changed)
+}
@@ -0,0 +1,103 @@
+package tracing
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	collectortracev1 "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	tracev1 "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/proto"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/c0deltin/replik8or/internal/config"
+)
+
+// collector is a stand-in for an OTLP/HTTP collector keeping all received spans.
+type collector struct {
+	mu    sync.Mutex
+	spans []*tracev1.Span
+}
+
+func (c *collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var req collectortracev1.ExportTraceServiceRequest
+	if err := proto.Unmarshal(body, &req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, resourceSpans := range req.GetResourceSpans() {
+		for _, scopeSpans := range resourceSpans.GetScopeSpans() {
+			c.spans = append(c.spans, scopeSpans.GetSpans()...)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/x-protobuf")
+	response, _ := proto.Marshal(&collectortracev1.ExportTraceServiceResponse{})
+	_, _ = w.Write(response)
+}
+
+func TestSetup(t *testing.T) {
+	t.Run("disabled", func(t *testing.T) {
+		shutdown, err := Setup(t.Context(), &config.Config{})
+
+		assert.NoError(t, err)
+		assert.NoError(t, shutdown(t.Context()))
+	})
+
+	t.Run("export to collector", func(t *testing.T) {
+		var c collector
+		server := httptest.NewServer(&c)
+		defer server.Close()
+
+		shutdown, err := Setup(t.Context(), &config.Config{
+			TracingEndpoint:    strings.TrimPrefix(server.URL, "http://"),
+			TracingInsecure:    true,
+			TracingSampleRatio: 1,
+		})
+		assert.NoError(t, err)
+
+		source := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "source-name", Namespace: "source-namespace"}}
+		_, span := otel.Tracer("testing").Start(t.Context(), "testing-span")
+		span.SetAttributes(SourceAttributes(source, "ConfigMap")...)
+		End(span, nil)
+
+		assert.NoError(t, shutdown(t.Context()))
+
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		assert.Len(t, c.spans, 1)
+		assert.Equal(t, "testing-span", c.spans[0].GetName())
+		assert.Equal(t, "source-namespace/source-name", c.spans[0].GetAttributes()[0].GetValue().GetStringValue())
+	})
+}
+
+func TestEnd(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	_, span := provider.Tracer("testing").Start(t.Context(), "testing-span")
+	End(span, errors.New("testing-error"))
+
+	assert.Len(t, recorder.Ended(), 1)
+	assert.Equal(t, codes.Error, recorder.Ended()[0].Status().Code)
+	assert.Equal(t, "testing-error", recorder.Ended()[0].Status().Description)
+}
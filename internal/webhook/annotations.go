@@ -0,0 +1,285 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"path"
+	"slices"
+	"strings"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/c0deltin/replik8or/internal/config"
+	"github.com/c0deltin/replik8or/internal/namespaces"
+	"github.com/c0deltin/replik8or/internal/replicator"
+)
+
+// annotationPrefix is the prefix of all annotations of replik8or.
+const annotationPrefix = "replik8or.c0deltin.dev/"
+
+// knownAnnotations are the annotations set by users as well as the ones written by the operator.
+var knownAnnotations = []string{
+	replicator.ReplicationAllowedAnnotation,
+	replicator.DesiredNamespacesAnnotation,
+	replicator.ReplicationAllowedNamespacesAnnotation,
+	replicator.ReplicateFromAnnotation,
+	replicator.RunAsAnnotation,
+	replicator.LastReplicationAnnotation,
+	replicator.ReplicatedToAnnotation,
+	replicator.FailedNamespacesAnnotation,
+	replicator.ReplicationErrorsAnnotation,
+	replicator.ConflictsAnnotation,
+	replicator.ContentHashAnnotation,
+	replicator.KeyFilterAnnotation,
+}
+
+// operatorAnnotations are written by the operator only and therefore never validated on updates, so the status of
+// a source can always be written back.
+var operatorAnnotations = []string{
+	replicator.LastReplicationAnnotation,
+	replicator.ReplicatedToAnnotation,
+	replicator.FailedNamespacesAnnotation,
+	replicator.ReplicationErrorsAnnotation,
+	replicator.ConflictsAnnotation,
+	replicator.ContentHashAnnotation,
+	replicator.KeyFilterAnnotation,
+}
+
+// sourceAnnotations mark an object as source of a replication.
+var sourceAnnotations = []string{
+	replicator.ReplicationAllowedAnnotation,
+	replicator.DesiredNamespacesAnnotation,
+	replicator.ReplicationAllowedNamespacesAnnotation,
+}
+
+// AnnotationValidator rejects ConfigMaps and Secrets with malformed replik8or annotations, which would otherwise be
+// silently ignored.
+// +kubebuilder:webhook:path=/validate--v1-configmap,mutating=false,failurePolicy=ignore,sideEffects=None,groups="",resources=configmaps,verbs=create;update,versions=v1,name=vconfigmap.replik8or.c0deltin.dev,admissionReviewVersions=v1
+// +kubebuilder:webhook:path=/validate--v1-secret,mutating=false,failurePolicy=ignore,sideEffects=None,groups="",resources=secrets,verbs=create;update,versions=v1,name=vsecret.replik8or.c0deltin.dev,admissionReviewVersions=v1
+type AnnotationValidator[T client.Object] struct {
+	// client creates the SubjectAccessReviews of the requesting users
+	client     client.Client
+	namespaces *namespaces.Index
+	config     *config.Config
+
+	emptyObjectFn func() T
+}
+
+func NewAnnotationValidator[T client.Object](
+	client client.Client,
+	namespaces *namespaces.Index,
+	config *config.Config,
+	emptyObjectFn func() T,
+) *AnnotationValidator[T] {
+	return &AnnotationValidator[T]{
+		client:        client,
+		namespaces:    namespaces,
+		config:        config,
+		emptyObjectFn: emptyObjectFn,
+	}
+}
+
+func (v *AnnotationValidator[T]) SetupWithManager(mgr manager.Manager) error {
+	return builder.WebhookManagedBy(mgr, v.emptyObjectFn()).
+		WithValidator(v).
+		Complete()
+}
+
+func (v *AnnotationValidator[T]) ValidateCreate(ctx context.Context, object T) (admission.Warnings, error) {
+	return v.validate(ctx, object, replik8orAnnotations(object))
+}
+
+// ValidateUpdate only validates annotations whose values changed, so objects whose desired namespaces were deleted in
+// the meantime can still be updated. The annotations written by the operator are never validated.
+func (v *AnnotationValidator[T]) ValidateUpdate(ctx context.Context, oldObject, newObject T) (admission.Warnings, error) {
+	oldAnnotations := replik8orAnnotations(oldObject)
+
+	changed := map[string]string{}
+	for key, value := range replik8orAnnotations(newObject) {
+		if oldValue, ok := oldAnnotations[key]; (ok && oldValue == value) || slices.Contains(operatorAnnotations, key) {
+			continue
+		}
+		changed[key] = value
+	}
+	if len(changed) == 0 {
+		return nil, nil
+	}
+	return v.validate(ctx, newObject, changed)
+}
+
+func (v *AnnotationValidator[T]) ValidateDelete(context.Context, T) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// validate returns an Invalid error listing all malformed annotations among the given annotations of object.
+func (v *AnnotationValidator[T]) validate(
+	ctx context.Context,
+	object T,
+	annotations map[string]string,
+) (admission.Warnings, error) {
+	basePath := field.NewPath("metadata", "annotations")
+
+	var (
+		errs     field.ErrorList
+		warnings admission.Warnings
+	)
+	for _, key := range slices.Sorted(maps.Keys(annotations)) {
+		value := annotations[key]
+		fieldPath := basePath.Key(key)
+
+		switch key {
+		case replicator.ReplicationAllowedAnnotation:
+			if value != "true" {
+				errs = append(errs, field.Invalid(fieldPath, value,
+					`must be "true", remove the annotation to disable the replication`))
+			}
+		case replicator.DesiredNamespacesAnnotation:
+			namespaceErrs, namespaceWarnings, err := v.validateDesiredNamespaces(fieldPath, value)
+			if err != nil {
+				return nil, err
+			}
+			errs = append(errs, namespaceErrs...)
+			warnings = append(warnings, namespaceWarnings...)
+		case replicator.ReplicationAllowedNamespacesAnnotation:
+			for _, pattern := range strings.Split(value, ",") {
+				if _, err := path.Match(strings.TrimSpace(pattern), ""); err != nil {
+					errs = append(errs, field.Invalid(fieldPath, value, "malformed pattern "+pattern))
+				}
+			}
+		case replicator.ReplicateFromAnnotation:
+			if _, err := replicator.ParseReplicateFrom(value); err != nil {
+				errs = append(errs, field.Invalid(fieldPath, value, err.Error()))
+			}
+		case replicator.RunAsAnnotation:
+			msgs := validation.IsDNS1123Subdomain(value)
+			for _, msg := range msgs {
+				errs = append(errs, field.Invalid(fieldPath, value, msg))
+			}
+			if len(msgs) > 0 {
+				continue
+			}
+
+			allowed, err := v.mayImpersonate(ctx, object.GetNamespace(), value)
+			if err != nil {
+				return nil, err
+			}
+			if !allowed {
+				errs = append(errs, field.Forbidden(fieldPath, fmt.Sprintf(
+					"requires the permission to impersonate ServiceAccount %s/%s", object.GetNamespace(), value)))
+			}
+		default:
+			if !slices.Contains(knownAnnotations, key) {
+				errs = append(errs, field.NotSupported(basePath, key, knownAnnotations))
+			}
+		}
+	}
+
+	if slices.Contains(v.config.DisallowedSourceNamespaces, object.GetNamespace()) &&
+		slices.ContainsFunc(sourceAnnotations, func(key string) bool { _, ok := annotations[key]; return ok }) {
+		errs = append(errs, field.Forbidden(basePath,
+			"replicating from namespace "+object.GetNamespace()+" is disallowed"))
+	}
+
+	if len(errs) == 0 {
+		return warnings, nil
+	}
+	return warnings, apierrors.NewInvalid(
+		schema.GroupKind{Kind: replicator.Kind(object)},
+		object.GetName(),
+		errs,
+	)
+}
+
+// validateDesiredNamespaces validates that all desired namespaces exist and are allowed. When the operator is
+// restricted to a fixed set of namespaces, any other namespace does not exist for it. Until the namespace index is
+// synced, e.g. while the operator starts or fails over, the existence is not validated and only a warning is returned,
+// so annotated objects can still be written.
+func (v *AnnotationValidator[T]) validateDesiredNamespaces(
+	fieldPath *field.Path,
+	value string,
+) (field.ErrorList, admission.Warnings, error) {
+	var (
+		errs     field.ErrorList
+		warnings admission.Warnings
+	)
+	for _, name := range strings.Split(value, ",") {
+		if msgs := validation.IsDNS1123Label(name); len(msgs) > 0 {
+			errs = append(errs, field.Invalid(fieldPath, value, "namespace "+name+": "+strings.Join(msgs, ", ")))
+			continue
+		}
+		if slices.Contains(v.config.DisallowedNamespaces, name) {
+			errs = append(errs, field.Invalid(fieldPath, value, "namespace "+name+" is disallowed"))
+			continue
+		}
+
+		_, ok, err := v.namespaces.Get(name)
+		if errors.Is(err, namespaces.ErrNotSynced) {
+			if len(warnings) == 0 {
+				warnings = append(warnings, fieldPath.String()+": the existence of the namespaces was not validated, "+
+					"as replik8or is starting")
+			}
+			continue
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		if !ok {
+			errs = append(errs, field.Invalid(fieldPath, value, "namespace "+name+" does not exist"))
+		}
+	}
+	return errs, warnings, nil
+}
+
+// mayImpersonate reports whether the user of the admission request may impersonate the ServiceAccount, whose
+// permissions are used to authorize the replication. Otherwise, anyone allowed to annotate a source could replicate
+// with the permissions of any ServiceAccount of its namespace.
+func (v *AnnotationValidator[T]) mayImpersonate(ctx context.Context, namespace, serviceAccount string) (bool, error) {
+	req, err := admission.RequestFromContext(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	extra := make(map[string]authorizationv1.ExtraValue, len(req.UserInfo.Extra))
+	for key, value := range req.UserInfo.Extra {
+		extra[key] = authorizationv1.ExtraValue(value)
+	}
+	review := &authorizationv1.SubjectAccessReview{
+		Spec: authorizationv1.SubjectAccessReviewSpec{
+			User:   req.UserInfo.Username,
+			UID:    req.UserInfo.UID,
+			Groups: req.UserInfo.Groups,
+			Extra:  extra,
+			ResourceAttributes: &authorizationv1.ResourceAttributes{
+				Namespace: namespace,
+				Verb:      "impersonate",
+				Resource:  "serviceaccounts",
+				Name:      serviceAccount,
+			},
+		},
+	}
+	if err := v.client.Create(ctx, review); err != nil {
+		return false, fmt.Errorf("reviewing impersonation of ServiceAccount %s/%s: %w", namespace, serviceAccount, err)
+	}
+	return review.Status.Allowed, nil
+}
+
+// replik8orAnnotations returns the annotations of object prefixed with annotationPrefix.
+func replik8orAnnotations(object client.Object) map[string]string {
+	annotations := map[string]string{}
+	for key, value := range object.GetAnnotations() {
+		if strings.HasPrefix(key, annotationPrefix) {
+			annotations[key] = value
+		}
+	}
+	return annotations
+}
@@ -0,0 +1,220 @@
+package webhook
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	admissionv1 "k8s.io/api/admission/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	toolscache "k8s.io/client-go/tools/cache"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/c0deltin/replik8or/internal/config"
+	"github.com/c0deltin/replik8or/internal/namespaces"
+	"github.com/c0deltin/replik8or/internal/replicator"
+)
+
+func TestAnnotationValidator_ValidateCreate(t *testing.T) {
+	fakeClient := fake.NewClientBuilder().
+		WithInterceptorFuncs(interceptor.Funcs{
+			Create: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.CreateOption) error {
+				review, ok := obj.(*authorizationv1.SubjectAccessReview)
+				if !ok {
+					return c.Create(ctx, obj, opts...)
+				}
+				review.Status.Allowed = review.Spec.User == "alice" && review.Spec.ResourceAttributes.Name == "replicator"
+				return nil
+			},
+		}).
+		Build()
+	ctx := admission.NewContextWithRequest(t.Context(), admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+		UserInfo: authenticationv1.UserInfo{Username: "alice"},
+	}})
+
+	v := NewAnnotationValidator(fakeClient, namespaces.NewStaticIndex("testing", "kube-system"), &config.Config{
+		DisallowedNamespaces:       []string{"kube-system"},
+		DisallowedSourceNamespaces: []string{"kube-public"},
+	}, replicator.EmptyConfigMap)
+
+	tests := []struct {
+		name        string
+		namespace   string
+		annotations map[string]string
+		expectErr   string
+	}{
+		{
+			name:      "valid source",
+			namespace: "default",
+			annotations: map[string]string{
+				replicator.ReplicationAllowedAnnotation:           "true",
+				replicator.DesiredNamespacesAnnotation:            "testing",
+				replicator.ReplicationAllowedNamespacesAnnotation: "team-*,ci",
+				replicator.RunAsAnnotation:                        "replicator",
+				"example.com/unrelated":                           "value",
+			},
+		},
+		{
+			name:        "valid target",
+			namespace:   "default",
+			annotations: map[string]string{replicator.ReplicateFromAnnotation: "infra/ca-bundle"},
+		},
+		{
+			name:        "malformed replication-allowed",
+			namespace:   "default",
+			annotations: map[string]string{replicator.ReplicationAllowedAnnotation: "yes"},
+			expectErr:   `metadata.annotations[replik8or.c0deltin.dev/replication-allowed]: Invalid value: "yes"`,
+		},
+		{
+			name:        "unknown key",
+			namespace:   "default",
+			annotations: map[string]string{"replik8or.c0deltin.dev/desired-namespace": "testing"},
+			expectErr:   `Unsupported value: "replik8or.c0deltin.dev/desired-namespace"`,
+		},
+		{
+			name:        "nonexistent desired namespace",
+			namespace:   "default",
+			annotations: map[string]string{replicator.DesiredNamespacesAnnotation: "testing,missing"},
+			expectErr:   "namespace missing does not exist",
+		},
+		{
+			name:        "disallowed desired namespace",
+			namespace:   "default",
+			annotations: map[string]string{replicator.DesiredNamespacesAnnotation: "kube-system"},
+			expectErr:   "namespace kube-system is disallowed",
+		},
+		{
+			name:        "malformed replicate-from",
+			namespace:   "default",
+			annotations: map[string]string{replicator.ReplicateFromAnnotation: "ca-bundle"},
+			expectErr:   "expected <namespace>/<name>",
+		},
+		{
+			name:        "run-as without permission to impersonate",
+			namespace:   "default",
+			annotations: map[string]string{replicator.RunAsAnnotation: "cluster-admin"},
+			expectErr:   "requires the permission to impersonate ServiceAccount default/cluster-admin",
+		},
+		{
+			name:        "malformed pattern",
+			namespace:   "default",
+			annotations: map[string]string{replicator.ReplicationAllowedNamespacesAnnotation: "team-["},
+			expectErr:   "malformed pattern team-[",
+		},
+		{
+			name:        "disallowed source namespace",
+			namespace:   "kube-public",
+			annotations: map[string]string{replicator.ReplicationAllowedAnnotation: "true"},
+			expectErr:   "replicating from namespace kube-public is disallowed",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			object := &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: "object", Namespace: tt.namespace, Annotations: tt.annotations},
+			}
+
+			_, err := v.ValidateCreate(ctx, object)
+			if tt.expectErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.True(t, apierrors.IsInvalid(err))
+			assert.ErrorContains(t, err, tt.expectErr)
+		})
+	}
+}
+
+func TestAnnotationValidator_ValidateUpdate(t *testing.T) {
+	v := NewAnnotationValidator(nil, namespaces.NewIndex(), &config.Config{}, replicator.EmptyConfigMap)
+
+	// the desired namespace was deleted after the source was created
+	oldObject := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "object",
+			Namespace:   "default",
+			Annotations: map[string]string{replicator.DesiredNamespacesAnnotation: "deleted"},
+		},
+	}
+
+	t.Run("unchanged annotations", func(t *testing.T) {
+		newObject := oldObject.DeepCopy()
+		newObject.Data = map[string]string{"foo": "bar"}
+
+		_, err := v.ValidateUpdate(t.Context(), oldObject, newObject)
+		assert.NoError(t, err)
+	})
+
+	t.Run("added annotation", func(t *testing.T) {
+		newObject := oldObject.DeepCopy()
+		newObject.Annotations[replicator.ReplicationAllowedAnnotation] = "true"
+
+		_, err := v.ValidateUpdate(t.Context(), oldObject, newObject)
+		assert.NoError(t, err)
+	})
+
+	t.Run("changed annotation", func(t *testing.T) {
+		newObject := oldObject.DeepCopy()
+		newObject.Annotations[replicator.DesiredNamespacesAnnotation] = "deleted,other"
+
+		_, err := v.ValidateUpdate(t.Context(), oldObject, newObject)
+		assert.ErrorContains(t, err, "namespace deleted does not exist")
+	})
+
+	t.Run("namespace index not synced", func(t *testing.T) {
+		index := namespaces.NewIndex()
+		require.NoError(t, index.SetupWithManager(t.Context(), &unsyncedManager{}))
+		v := NewAnnotationValidator(nil, index, &config.Config{}, replicator.EmptyConfigMap)
+
+		newObject := oldObject.DeepCopy()
+		newObject.Annotations[replicator.DesiredNamespacesAnnotation] = "deleted,other"
+
+		warnings, err := v.ValidateUpdate(t.Context(), oldObject, newObject)
+		assert.NoError(t, err)
+		assert.Len(t, warnings, 1)
+	})
+
+	t.Run("status written by operator", func(t *testing.T) {
+		newObject := oldObject.DeepCopy()
+		newObject.Annotations[replicator.LastReplicationAnnotation] = "malformed"
+		newObject.Annotations[replicator.ReplicationErrorsAnnotation] = "deleted: namespace not found"
+
+		_, err := v.ValidateUpdate(t.Context(), oldObject, newObject)
+		assert.NoError(t, err)
+	})
+}
+
+// unsyncedManager provides a namespace informer which never syncs, like one of an operator which is starting.
+type unsyncedManager struct{ manager.Manager }
+
+func (m *unsyncedManager) GetCache() cache.Cache {
+	return &unsyncedCache{}
+}
+
+type unsyncedCache struct{ cache.Cache }
+
+func (c *unsyncedCache) GetInformer(context.Context, client.Object, ...cache.InformerGetOption) (cache.Informer, error) {
+	return &unsyncedInformer{}, nil
+}
+
+type unsyncedInformer struct{ cache.Informer }
+
+func (i *unsyncedInformer) AddEventHandler(
+	toolscache.ResourceEventHandler,
+) (toolscache.ResourceEventHandlerRegistration, error) {
+	return i, nil
+}
+
+func (i *unsyncedInformer) HasSynced() bool {
+	return false
+}
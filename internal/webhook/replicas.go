@@ -0,0 +1,107 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/c0deltin/replik8or/internal/config"
+	"github.com/c0deltin/replik8or/internal/replicator"
+)
+
+// namespaceController deletes the content of terminating namespaces, which must never be blocked.
+const namespaceController = "system:serviceaccount:kube-system:namespace-controller"
+
+// ReplicaValidator denies updates and deletions of replicas, unless requested by the operator itself or a member of
+// the configured break-glass groups. Changes must be made to the source instead.
+// +kubebuilder:webhook:path=/protect--v1-configmap,mutating=false,failurePolicy=ignore,sideEffects=None,groups="",resources=configmaps,verbs=update;delete,versions=v1,name=pconfigmap.replik8or.c0deltin.dev,admissionReviewVersions=v1
+// +kubebuilder:webhook:path=/protect--v1-secret,mutating=false,failurePolicy=ignore,sideEffects=None,groups="",resources=secrets,verbs=update;delete,versions=v1,name=psecret.replik8or.c0deltin.dev,admissionReviewVersions=v1
+type ReplicaValidator[T client.Object] struct {
+	config *config.Config
+	// operator is the username the operator is authenticated as.
+	operator string
+
+	emptyObjectFn func() T
+}
+
+func NewReplicaValidator[T client.Object](
+	config *config.Config,
+	operator string,
+	emptyObjectFn func() T,
+) *ReplicaValidator[T] {
+	return &ReplicaValidator[T]{
+		config:        config,
+		operator:      operator,
+		emptyObjectFn: emptyObjectFn,
+	}
+}
+
+func (v *ReplicaValidator[T]) SetupWithManager(mgr manager.Manager) error {
+	return builder.WebhookManagedBy(mgr, v.emptyObjectFn()).
+		WithValidator(v).
+		WithValidatorCustomPath("/protect--v1-" + resourceName(v.emptyObjectFn())).
+		Complete()
+}
+
+func (v *ReplicaValidator[T]) ValidateCreate(context.Context, T) (admission.Warnings, error) {
+	return nil, nil
+}
+
+func (v *ReplicaValidator[T]) ValidateUpdate(ctx context.Context, oldObject, _ T) (admission.Warnings, error) {
+	return nil, v.validate(ctx, oldObject, false)
+}
+
+func (v *ReplicaValidator[T]) ValidateDelete(ctx context.Context, object T) (admission.Warnings, error) {
+	return nil, v.validate(ctx, object, true)
+}
+
+// validate returns a Forbidden error if object is a replica and the request is not allowed to modify it.
+func (v *ReplicaValidator[T]) validate(ctx context.Context, object T, deletion bool) error {
+	if !replicator.HasLabels(object, replicator.SourceNamespaceLabel, replicator.SourceNameLabel) {
+		return nil
+	}
+
+	req, err := admission.RequestFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	user := req.UserInfo
+	if user.Username == v.operator ||
+		(deletion && user.Username == namespaceController) ||
+		slices.ContainsFunc(user.Groups, func(group string) bool {
+			return slices.Contains(v.config.BreakGlassGroups, group)
+		}) {
+		return nil
+	}
+
+	labels := object.GetLabels()
+	return apierrors.NewForbidden(
+		schema.GroupResource{Resource: resourceName(object)},
+		object.GetName(),
+		fmt.Errorf("object is a replica of %s/%s managed by replik8or, modify the source instead",
+			labels[replicator.SourceNamespaceLabel], labels[replicator.SourceNameLabel]),
+	)
+}
+
+// OperatorUsername returns the username the client is authenticated as.
+func OperatorUsername(ctx context.Context, c client.Client) (string, error) {
+	review := &authenticationv1.SelfSubjectReview{}
+	if err := c.Create(ctx, review); err != nil {
+		return "", fmt.Errorf("reviewing own identity: %w", err)
+	}
+	return review.Status.UserInfo.Username, nil
+}
+
+// resourceName returns the lowercase plural name of the resource of object.
+func resourceName(object client.Object) string {
+	return strings.ToLower(replicator.Kind(object)) + "s"
+}
@@ -0,0 +1,81 @@
+package webhook
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	admissionv1 "k8s.io/api/admission/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/c0deltin/replik8or/internal/config"
+	"github.com/c0deltin/replik8or/internal/replicator"
+)
+
+func requestContext(ctx context.Context, username string, groups ...string) context.Context {
+	return admission.NewContextWithRequest(ctx, admission.Request{
+		AdmissionRequest: admissionv1.AdmissionRequest{
+			UserInfo: authenticationv1.UserInfo{Username: username, Groups: groups},
+		},
+	})
+}
+
+func TestReplicaValidator(t *testing.T) {
+	operator := "system:serviceaccount:replik8or:replik8or"
+	v := NewReplicaValidator(&config.Config{BreakGlassGroups: []string{"admins"}}, operator, replicator.EmptySecret)
+
+	replica := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "secret",
+			Namespace: "testing",
+			Labels: map[string]string{
+				replicator.SourceNamespaceLabel: "infra",
+				replicator.SourceNameLabel:      "secret",
+			},
+		},
+	}
+
+	t.Run("deny update by tenant", func(t *testing.T) {
+		ctx := requestContext(t.Context(), "jane", "system:authenticated")
+		_, err := v.ValidateUpdate(ctx, replica, replica)
+
+		assert.True(t, apierrors.IsForbidden(err))
+		assert.ErrorContains(t, err, "object is a replica of infra/secret managed by replik8or, modify the source instead")
+	})
+
+	t.Run("deny delete by tenant", func(t *testing.T) {
+		_, err := v.ValidateDelete(requestContext(t.Context(), "jane"), replica)
+		assert.True(t, apierrors.IsForbidden(err))
+	})
+
+	t.Run("allow operator", func(t *testing.T) {
+		ctx := requestContext(t.Context(), operator)
+		_, err := v.ValidateUpdate(ctx, replica, replica)
+		assert.NoError(t, err)
+		_, err = v.ValidateDelete(ctx, replica)
+		assert.NoError(t, err)
+	})
+
+	t.Run("allow break-glass group", func(t *testing.T) {
+		_, err := v.ValidateUpdate(requestContext(t.Context(), "jane", "admins"), replica, replica)
+		assert.NoError(t, err)
+	})
+
+	t.Run("allow namespace deletion", func(t *testing.T) {
+		ctx := requestContext(t.Context(), namespaceController)
+		_, err := v.ValidateDelete(ctx, replica)
+		assert.NoError(t, err)
+		_, err = v.ValidateUpdate(ctx, replica, replica)
+		assert.Error(t, err)
+	})
+
+	t.Run("ignore objects which are no replicas", func(t *testing.T) {
+		object := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "secret", Namespace: "testing"}}
+		_, err := v.ValidateDelete(requestContext(t.Context(), "jane"), object)
+		assert.NoError(t, err)
+	})
+}